@@ -3,12 +3,29 @@ package main
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 )
 
 type CmdArgs struct {
-	Filename    string
-	DefsPath    string
+	Command     string
+	Filenames   []string
+	DefsPaths   []string
 	ShowAll     bool
+	MaxBytes    int
+	Color       ColorMode
+	OnlyName    bool
+	OnlyMime    bool
+	Recurse     bool
+	Progress    bool
+	FollowLinks bool
+	CheckVer    bool
+	Strict      bool
+	Vars        map[string]string
+	Benchmark   bool
+	Describe    bool
+	NoExtract   bool
+	BestEffort  bool
 	ShowHelp    bool
 	ShowVersion bool
 }
@@ -18,32 +35,69 @@ func ShowHelp() {
 
 	fmt.Println("The Binary Identifier for determining file types")
 	fmt.Println()
-	fmt.Println("usage: binid [options] [filename]")
+	fmt.Println("usage: binid [options] [filename ...]")
+	fmt.Println("       binid dump [definition ...]")
+	fmt.Println()
+	fmt.Println("commands:")
+	fmt.Println("  dump              print the fields declared by the given definition")
+	fmt.Println("                    file(s) without matching them against a file")
 	fmt.Println()
 	fmt.Println("arguments:")
-	fmt.Println("  filename          path of the file to identify")
+	fmt.Println("  filename          path of the file(s) to identify")
 	fmt.Println()
 	fmt.Println("options:")
 	fmt.Println("  -h, --help        show this help message")
 	fmt.Println("  -a, --all         show all bytes of a byte sequence")
 	fmt.Println("                    (this may produce large outputs)")
+	fmt.Println("  --describe        show the documentation of fields alongside their values")
+	fmt.Println("  --best-effort     show the fields extracted before a field fails")
+	fmt.Println("                    (by default, the definition is reported as failed)")
+	fmt.Println("  --no-extract      only identify the format without extracting its fields")
+	fmt.Println("                    (reads up to the last magic field of each definition)")
+	fmt.Println("  -m, --max-bytes   number of bytes of a byte sequence to show")
+	fmt.Println("                    (default is 256)")
+	fmt.Println("  --color           always colorize the output")
+	fmt.Println("  --no-color        never colorize the output")
+	fmt.Println("                    (default is to colorize if printing to a terminal")
+	fmt.Println("                    unless the NO_COLOR environment variable is non-empty)")
+	fmt.Println("  -r, --recurse     identify every file within directories given as input")
+	fmt.Println("                    (prints a one-line summary per file)")
+	fmt.Println("  --follow-symlinks follow symbolic links within directories given as input")
+	fmt.Println("                    (by default, they are reported and skipped)")
+	fmt.Println("  --progress        report the number of files scanned so far on stderr")
+	fmt.Println("                    (only applies with -r)")
+	fmt.Println("  --name            print only the name of the best match")
+	fmt.Println("  --mime            print only the MIME type of the best match")
+	fmt.Println("                    (exits with a non-zero status if nothing matched)")
+	fmt.Println("  --benchmark       show how long each definition took to match")
+	fmt.Println("  --version-check   skip definitions written for a newer BDF version")
+	fmt.Println("                    (by default, only a warning is shown)")
+	fmt.Println("  --strict          skip definitions containing keys unknown to BDF")
+	fmt.Println("                    (by default, only a warning is shown)")
+	fmt.Println("  --var KEY=VALUE   make VALUE available to definitions as KEY")
+	fmt.Println("                    (may be repeated; integers are passed as numbers)")
 	fmt.Println("  -d, --defs        path to the definitions folder")
-	fmt.Println("                    (default is 'formats' in current directory)")
+	fmt.Println("                    (may be repeated or comma-separated to merge folders;")
+	fmt.Println("                    default is 'formats' in current directory)")
 	fmt.Println("  -v, --version     print binid's version")
 }
 
 func ParseCmdArgs(args []string) CmdArgs {
 	if len(os.Args) < 2 {
 		fmt.Println("BinID version", VERSION)
-		fmt.Println("usage: binid [options] [filename]. see binid -h for help.")
+		fmt.Println("usage: binid [options] [filename ...]. see binid -h for help.")
 		os.Exit(1)
 	}
 
-	cmd := CmdArgs{}
+	cmd := CmdArgs{MaxBytes: 256, Color: ColorAuto}
 
 	argPosition := 0
-	done := false
-	for argPosition < len(args) && !done {
+	if len(args) > 0 && args[0] == "dump" {
+		cmd.Command = "dump"
+		argPosition++
+	}
+
+	for argPosition < len(args) {
 		switch arg := args[argPosition]; arg {
 		case "-h", "--help":
 			cmd.ShowHelp = true
@@ -51,6 +105,32 @@ func ParseCmdArgs(args []string) CmdArgs {
 			cmd.ShowAll = true
 		case "-v", "--version":
 			cmd.ShowVersion = true
+		case "--color":
+			cmd.Color = ColorAlways
+		case "--no-color":
+			cmd.Color = ColorNever
+		case "-r", "--recurse":
+			cmd.Recurse = true
+		case "--follow-symlinks":
+			cmd.FollowLinks = true
+		case "--progress":
+			cmd.Progress = true
+		case "--best-effort":
+			cmd.BestEffort = true
+		case "--no-extract":
+			cmd.NoExtract = true
+		case "--describe":
+			cmd.Describe = true
+		case "--benchmark":
+			cmd.Benchmark = true
+		case "--version-check":
+			cmd.CheckVer = true
+		case "--strict":
+			cmd.Strict = true
+		case "--name":
+			cmd.OnlyName = true
+		case "--mime":
+			cmd.OnlyMime = true
 		case "-d", "--defs":
 			if argPosition+1 >= len(args) {
 				fmt.Println("error: missing value for option 'defs'")
@@ -58,18 +138,49 @@ func ParseCmdArgs(args []string) CmdArgs {
 			}
 
 			argPosition++
-			cmd.DefsPath = args[argPosition]
-		default:
-			if cmd.Filename == "" {
-				cmd.Filename = arg
-			} else {
-				done = true
+			for _, path := range strings.Split(args[argPosition], ",") {
+				if path != "" {
+					cmd.DefsPaths = append(cmd.DefsPaths, path)
+				}
+			}
+		case "--var":
+			if argPosition+1 >= len(args) {
+				fmt.Println("error: missing value for option 'var'")
+				os.Exit(1)
+			}
+
+			argPosition++
+			key, value, found := strings.Cut(args[argPosition], "=")
+			if !found || key == "" {
+				fmt.Println("error: value for option 'var' must be of the form KEY=VALUE")
+				os.Exit(1)
 			}
+
+			if cmd.Vars == nil {
+				cmd.Vars = map[string]string{}
+			}
+			cmd.Vars[key] = value
+		case "-m", "--max-bytes":
+			if argPosition+1 >= len(args) {
+				fmt.Println("error: missing value for option 'max-bytes'")
+				os.Exit(1)
+			}
+
+			argPosition++
+			maxBytes, err := strconv.Atoi(args[argPosition])
+			if err != nil || maxBytes < 0 {
+				fmt.Println("error: value for option 'max-bytes' must be a non-negative integer")
+				os.Exit(1)
+			}
+
+			cmd.MaxBytes = maxBytes
+		default:
+			cmd.Filenames = append(cmd.Filenames, arg)
 		}
 		argPosition += 1
 	}
 
-	if !(cmd.ShowHelp || cmd.ShowVersion) && cmd.Filename == "" {
+	if !(cmd.ShowHelp || cmd.ShowVersion) && len(cmd.Filenames) == 0 {
 		fmt.Println("error: missing required argument 'filename'")
 		fmt.Println("see binid -h for help")
 		os.Exit(1)
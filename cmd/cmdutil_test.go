@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"testing"
+)
+
+// parseArgs runs ParseCmdArgs over args as if they were given in the command line.
+func parseArgs(t *testing.T, args ...string) CmdArgs {
+	t.Helper()
+
+	osArgs := os.Args
+	os.Args = append([]string{"binid"}, args...)
+	defer func() { os.Args = osArgs }()
+
+	return ParseCmdArgs(args)
+}
+
+func TestParseCmdArgsMaxBytes(t *testing.T) {
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"file"}, 256},
+		{[]string{"-m", "16", "file"}, 16},
+		{[]string{"file", "--max-bytes", "0"}, 0},
+	}
+
+	for _, tt := range tests {
+		args := parseArgs(t, tt.args...)
+		if args.MaxBytes != tt.want || !slices.Equal(args.Filenames, []string{"file"}) {
+			t.Errorf("%q: got max bytes %d and files %q", tt.args, args.MaxBytes, args.Filenames)
+		}
+	}
+}
+
+func TestParseCmdArgsFilenames(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"a"}, []string{"a"}},
+		{[]string{"a", "b", "c"}, []string{"a", "b", "c"}},
+		{[]string{"a", "-m", "4", "b", "--name", "c"}, []string{"a", "b", "c"}},
+		{[]string{"-d", "defs", "a"}, []string{"a"}},
+	}
+
+	for _, tt := range tests {
+		if got := parseArgs(t, tt.args...).Filenames; !slices.Equal(got, tt.want) {
+			t.Errorf("%q: got files %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
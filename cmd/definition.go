@@ -0,0 +1,722 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aescarias/bindef/bindef"
+)
+
+// A Definition is a compiled BDF document along with its metadata.
+type Definition struct {
+	Document   bindef.Result
+	Path       string   // Location the definition was loaded from, if any.
+	Warnings   []string // Suspicious but valid constructs found in the document.
+	Priority   int      // Matches of definitions with higher priorities are ranked first.
+	Supersedes []string // Names of formats that this definition outranks when both match.
+	Unknown    []string // Keys not recognized by the bindef runtime, which ignores them.
+	meta       bindef.Meta
+	vars       bindef.ListResult
+	noExtract  bool
+	partial    bool
+}
+
+// NewDefinition creates a definition from an evaluated BDF document. An error is
+// returned if the document's metadata is not valid.
+func NewDefinition(document bindef.Result) (def *Definition, err error) {
+	defer recoverMalformed(&err)
+
+	meta, err := bindef.GetMetadata(document)
+	if err != nil {
+		return nil, fmt.Errorf("metadata get failed: %w", err)
+	}
+
+	def = &Definition{Document: document, meta: meta}
+	if err := def.parseRanking(); err != nil {
+		return nil, fmt.Errorf("meta: %w", err)
+	}
+
+	if err := checkDocument(document, "", &def.Warnings, &def.Unknown); err != nil {
+		return nil, err
+	}
+
+	def.Warnings = append(def.Warnings, def.Unknown...)
+
+	if err := checkVersion(meta); err != nil {
+		def.Warnings = append(def.Warnings, err.Error())
+	}
+
+	return def, nil
+}
+
+// checkVersion reports an error if the BDF version declared by meta is newer than
+// the version implemented by the bindef runtime.
+func checkVersion(meta bindef.Meta) error {
+	version, spec := meta.Version, bindef.SpecVersion
+	if version.Major > spec.Major || (version.Major == spec.Major && version.Minor > spec.Minor) {
+		return fmt.Errorf("requires BDF %s but only BDF %s is supported", version, spec)
+	}
+
+	return nil
+}
+
+// parseRanking reads the optional 'priority' and 'supersedes' keys of the
+// document's metadata. These are not part of the BDF spec and are only used by
+// binid when ranking matches.
+func (d *Definition) parseRanking() error {
+	root, _ := d.Document.(bindef.MapResult)
+	metaMap, _ := root[bindef.IdentResult("meta")].(bindef.MapResult)
+
+	if res, ok := metaMap[bindef.IdentResult("priority")]; ok {
+		priority, ok := res.(bindef.IntegerResult)
+		if !ok || !priority.IsInt64() {
+			return fmt.Errorf("priority must be an integer")
+		}
+		d.Priority = int(priority.Int64())
+	}
+
+	if res, ok := metaMap[bindef.IdentResult("supersedes")]; ok {
+		supersedes, ok := res.(bindef.ListResult)
+		if !ok {
+			return fmt.Errorf("supersedes must be a list of format names")
+		}
+
+		for idx, item := range supersedes {
+			name, ok := item.(bindef.StringResult)
+			if !ok {
+				return fmt.Errorf("supersedes[%d]: expected result of type %s, received %s", idx, bindef.ResultString, item.Kind())
+			}
+			d.Supersedes = append(d.Supersedes, string(name))
+		}
+	}
+
+	return nil
+}
+
+// Meta returns the metadata of the definition.
+func (d *Definition) Meta() bindef.Meta {
+	return d.meta
+}
+
+// SetVar makes value available to the expressions in the definition under name
+// when it is matched.
+func (d *Definition) SetVar(name string, value bindef.Result) {
+	d.vars = append(d.vars, bindef.MapResult{
+		bindef.IdentResult("type"): bindef.TypeResult{Name: bindef.TypeVar},
+		bindef.IdentResult("id"): bindef.LazyResult(func(bindef.Namespace) (bindef.Result, error) {
+			return bindef.IdentResult(name), nil
+		}),
+		bindef.IdentResult("value"): value,
+	})
+}
+
+// SkipExtraction makes the definition stop matching a file once the last field
+// asserting a magic value is read, which is enough to determine whether the file
+// is of its format. Only the fields read up to that point are returned by Match.
+//
+// Definitions without magic fields are still matched in full, as any of their
+// fields may determine whether a file matches.
+func (d *Definition) SkipExtraction() {
+	d.noExtract = true
+}
+
+// AllowPartial makes Match return the fields extracted before a field in the
+// binary section fails, along with an [ErrPartial] describing the failure. A
+// magic mismatch or a failure in the first field is still returned as is.
+func (d *Definition) AllowPartial() {
+	d.partial = true
+}
+
+// ErrPartial is returned by [Definition.Match] when a field fails after others
+// were extracted and partial results are allowed.
+type ErrPartial struct {
+	Err error // The error raised by the failing field.
+}
+
+func (e ErrPartial) Error() string {
+	return e.Err.Error()
+}
+
+func (e ErrPartial) Unwrap() error {
+	return e.Err
+}
+
+// Match applies the definition to the file at filename and returns the metadata
+// extracted from it.
+func (d *Definition) Match(filename string) (pairs []bindef.MetaPair, err error) {
+	defer recoverMalformed(&err)
+
+	document, ok := d.Document.(bindef.MapResult)
+	if !ok {
+		return bindef.ApplyBDF(d.Document, filename)
+	}
+
+	binary, _ := document[bindef.IdentResult("binary")].(bindef.ListResult)
+	if d.noExtract {
+		types, _ := document[bindef.IdentResult("types")].(bindef.ListResult)
+		binary = binary[:magicPrefix(binary, types)]
+	}
+
+	pairs, err = d.apply(document, binary, filename)
+	if err == nil || !d.partial {
+		return pairs, err
+	}
+
+	// bindef does not return the fields read before a failure, so they are
+	// extracted again by applying only the fields preceding the failing one.
+	idx, ok := failedField(err)
+	if !ok || idx == 0 {
+		return nil, err
+	}
+
+	pairs, prefixErr := d.apply(document, binary[:idx], filename)
+	if prefixErr != nil {
+		return nil, err
+	}
+
+	return pairs, ErrPartial{Err: err}
+}
+
+// apply applies document to the file at filename with its binary section replaced
+// by binary.
+func (d *Definition) apply(document bindef.MapResult, binary bindef.ListResult, filename string) ([]bindef.MetaPair, error) {
+	// variables are declared as var fields ahead of the binary section, since
+	// the namespace used by bindef cannot be reached otherwise.
+	document = maps.Clone(document)
+	document[bindef.IdentResult("binary")] = slices.Concat(d.vars, binary)
+
+	pairs, err := bindef.ApplyBDF(document, filename)
+	if err != nil {
+		return nil, d.unshiftError(err)
+	}
+
+	return pairs[len(d.vars):], nil
+}
+
+// magicPrefix returns the number of fields in binary up to and including the last
+// one that may assert a magic value, or all of them if none do. Fields whose type
+// refers to an entry in types are checked against that entry.
+func magicPrefix(binary, types bindef.ListResult) int {
+	search := newMagicSearch(types)
+	for idx := len(binary) - 1; idx >= 0; idx-- {
+		if search.hasMagic(binary[idx]) {
+			return idx + 1
+		}
+	}
+
+	return len(binary)
+}
+
+// A magicSearch looks for format types with a magic key, following references to
+// the entries in the types section of a document.
+type magicSearch struct {
+	types bindef.Namespace            // Entries in the types section by id.
+	found map[bindef.IdentResult]bool // Whether each entry checked so far has a magic key.
+
+	// typesMagic is set if any entry in the types section has a magic key. A type
+	// that cannot be resolved without reading the file may refer to any entry, so
+	// it is assumed to have a magic key if this is set.
+	typesMagic bool
+}
+
+func newMagicSearch(types bindef.ListResult) *magicSearch {
+	search := &magicSearch{
+		types: bindef.Namespace{bindef.IdentResult("eos"): bindef.IdentResult("eos")},
+		found: map[bindef.IdentResult]bool{},
+	}
+
+	for _, res := range types {
+		entry, ok := res.(bindef.MapResult)
+		if !ok {
+			continue
+		}
+
+		if id, ok := evalKey(entry, "id").(bindef.IdentResult); ok {
+			search.types[id] = entry
+		}
+
+		search.typesMagic = search.typesMagic || hasMagicKey(entry)
+	}
+
+	return search
+}
+
+// hasMagic reports whether res is or contains a format type with a magic key,
+// including through the types it refers to.
+func (s *magicSearch) hasMagic(res bindef.Result) bool {
+	switch r := res.(type) {
+	case bindef.MapResult:
+		if _, ok := r[bindef.IdentResult("magic")]; ok {
+			return true
+		}
+
+		for key, value := range r {
+			switch key {
+			case bindef.IdentResult("type"), bindef.IdentResult("default"):
+				if s.refHasMagic(value) {
+					return true
+				}
+			case bindef.IdentResult("cases"):
+				// each case may be a format type or an expression naming one.
+				if cases, ok := value.(bindef.MapResult); ok {
+					for _, format := range cases {
+						if s.refHasMagic(format) {
+							return true
+						}
+					}
+				}
+			default:
+				if s.hasMagic(value) {
+					return true
+				}
+			}
+		}
+	case bindef.ListResult:
+		return slices.ContainsFunc(r, s.hasMagic)
+	}
+
+	return false
+}
+
+// refHasMagic reports whether the type given by res, which may be an expression
+// naming an entry in the types section, has a magic key.
+func (s *magicSearch) refHasMagic(res bindef.Result) bool {
+	lazy, ok := res.(bindef.LazyResult)
+	if !ok {
+		return s.hasMagic(res)
+	}
+
+	evalRes, err := lazy(s.types)
+	if err != nil {
+		return s.typesMagic
+	}
+
+	entry, ok := evalRes.(bindef.MapResult)
+	if !ok {
+		return false
+	}
+
+	id, ok := evalKey(entry, "id").(bindef.IdentResult)
+	if !ok {
+		return s.hasMagic(entry)
+	}
+
+	if found, ok := s.found[id]; ok {
+		return found
+	}
+
+	// entries may refer to themselves, so the entry is marked as checked before
+	// its fields are.
+	s.found[id] = false
+	s.found[id] = s.hasMagic(entry)
+	return s.found[id]
+}
+
+// hasMagicKey reports whether res is or contains a map with a magic key, without
+// following type references.
+func hasMagicKey(res bindef.Result) bool {
+	switch r := res.(type) {
+	case bindef.MapResult:
+		if _, ok := r[bindef.IdentResult("magic")]; ok {
+			return true
+		}
+
+		for _, value := range r {
+			if hasMagicKey(value) {
+				return true
+			}
+		}
+	case bindef.ListResult:
+		return slices.ContainsFunc(r, hasMagicKey)
+	}
+
+	return false
+}
+
+// failedField returns the index of the field in the binary section that err was
+// raised by, if err names one.
+func failedField(err error) (int, bool) {
+	var idx int
+	if _, scanErr := fmt.Sscanf(err.Error(), "binary[%d]:", &idx); scanErr != nil {
+		return 0, false
+	}
+
+	return idx, true
+}
+
+// unshiftError corrects the index of the binary field reported by err, which is
+// offset by the variables declared ahead of the binary section.
+func (d *Definition) unshiftError(err error) error {
+	idx, ok := failedField(err)
+	if !ok || idx < len(d.vars) {
+		return err
+	}
+
+	inner := errors.Unwrap(err)
+	if inner == nil {
+		return err
+	}
+
+	return fmt.Errorf("binary[%d]: %w", idx-len(d.vars), inner)
+}
+
+// A Match is the result of successfully applying a definition to a file.
+type Match struct {
+	Name    string            // The file name of the matching definition.
+	Def     *Definition       // The matching definition.
+	Pairs   []bindef.MetaPair // The metadata extracted from the file.
+	Partial error             // If set, the error that stopped extraction early.
+}
+
+// Meta returns the metadata of the matching definition.
+func (m Match) Meta() bindef.Meta {
+	return m.Def.Meta()
+}
+
+// MatchDefinition applies def, known by name, to the file at filename.
+func MatchDefinition(name string, def *Definition, filename string) (Match, error) {
+	pairs, err := def.Match(filename)
+
+	var perr ErrPartial
+	if errors.As(err, &perr) {
+		return Match{Name: name, Def: def, Pairs: pairs, Partial: perr.Err}, nil
+	} else if err != nil {
+		return Match{}, err
+	}
+
+	return Match{Name: name, Def: def, Pairs: pairs}, nil
+}
+
+// MatchAll applies every definition in defs to the file at filename and returns
+// the matches as ranked by [RankMatches]. Definitions that fail for a reason other
+// than a magic mismatch are returned in failed, keyed by name.
+//
+// If timings is not nil, the time taken to apply each definition is recorded in
+// it, keyed by name.
+func MatchAll(defs map[string]*Definition, filename string, timings map[string]time.Duration) (matches []Match, failed map[string]error) {
+	failed = map[string]error{}
+	for _, name := range slices.Sorted(maps.Keys(defs)) {
+		start := time.Now()
+		match, err := MatchDefinition(name, defs[name], filename)
+		if timings != nil {
+			timings[name] = time.Since(start)
+		}
+
+		if err != nil {
+			if _, ok := err.(bindef.ErrMagic); !ok {
+				failed[name] = err
+			}
+			continue
+		}
+
+		matches = append(matches, match)
+	}
+
+	return RankMatches(matches), failed
+}
+
+// RankMatches sorts matches with complete matches ahead of partial ones, then by
+// descending priority, then by definition name, and removes the matches whose
+// format is superseded by another match.
+//
+// Supersession is resolved in that order: a match that has already been removed
+// does not remove others, and a match naming its own format is ignored. Hence at
+// least one match is kept if any are given, even if two formats supersede each
+// other.
+func RankMatches(matches []Match) []Match {
+	sorted := slices.Clone(matches)
+	slices.SortStableFunc(sorted, func(a, b Match) int {
+		if a.Partial == nil && b.Partial != nil {
+			return -1
+		} else if a.Partial != nil && b.Partial == nil {
+			return 1
+		}
+
+		if c := cmp.Compare(b.Def.Priority, a.Def.Priority); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	superseded := map[string]bool{}
+	for _, match := range sorted {
+		format := match.Def.Meta().Name
+		if superseded[format] {
+			continue
+		}
+
+		for _, name := range match.Def.Supersedes {
+			if name != format {
+				superseded[name] = true
+			}
+		}
+	}
+
+	ranked := []Match{}
+	for _, match := range sorted {
+		if !superseded[match.Def.Meta().Name] {
+			ranked = append(ranked, match)
+		}
+	}
+
+	return ranked
+}
+
+// recoverMalformed recovers from a panic raised by bindef when a key in a document
+// has an unexpected type and stores it in err instead, so that a single malformed
+// definition does not bring down the whole program.
+func recoverMalformed(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("malformed definition: %v", r)
+	}
+}
+
+// CompileDef lexes, parses and evaluates the BDF source in src and returns the
+// resulting definition. Errors are returned unwrapped so that they can be passed
+// to [bindef.ReportError] along with src.
+func CompileDef(src []byte) (*Definition, error) {
+	lex := bindef.NewLexer(src)
+
+	if err := lex.Process(); err != nil {
+		return nil, err
+	}
+
+	ps := bindef.NewParser(lex.Tokens)
+
+	tree, err := ps.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	document, err := bindef.Evaluate(tree, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDefinition(document)
+}
+
+// checkDocument walks res and validates the field lists and enum members found
+// within. Problems that make the document ambiguous are returned as errors, while
+// suspicious but valid constructs are appended to warnings.
+//
+// Field ids declared more than once within the same 'binary' or 'fields' list are
+// an error. Throwaway ids (those starting with an underscore) and fields guarded by
+// an 'if' condition are exempt, as the latter commonly describe mutually exclusive
+// variants of the same field.
+func checkDocument(res bindef.Result, path string, warnings, unknown *[]string) error {
+	switch r := res.(type) {
+	case bindef.MapResult:
+		for _, key := range sortedKeys(r) {
+			keyPath := fmt.Sprintf("%v", key)
+			if path != "" {
+				keyPath = path + "." + keyPath
+			}
+
+			switch value := r[key].(type) {
+			case bindef.ListResult:
+				switch key {
+				case bindef.IdentResult("binary"), bindef.IdentResult("fields"):
+					if err := checkDuplicateIds(value, keyPath); err != nil {
+						return err
+					}
+					checkKeysIn(value, keyPath, formatKeys, unknown)
+				case bindef.IdentResult("types"):
+					checkKeysIn(value, keyPath, formatKeys, unknown)
+				case bindef.IdentResult("members"):
+					if err := checkEnumMembers(value, keyPath, warnings); err != nil {
+						return err
+					}
+					checkKeysIn(value, keyPath, memberKeys, unknown)
+				}
+			case bindef.MapResult:
+				switch key {
+				case bindef.IdentResult("item"), bindef.IdentResult("default"):
+					checkKeys(value, keyPath, formatKeys, unknown)
+				case bindef.IdentResult("cases"):
+					for _, caseKey := range sortedKeys(value) {
+						if format, ok := value[caseKey].(bindef.MapResult); ok {
+							checkKeys(format, fmt.Sprintf("%s.%v", keyPath, caseKey), formatKeys, unknown)
+						}
+					}
+				}
+			}
+
+			if err := checkDocument(r[key], keyPath, warnings, unknown); err != nil {
+				return err
+			}
+		}
+	case bindef.ListResult:
+		for idx, item := range r {
+			if err := checkDocument(item, fmt.Sprintf("%s[%d]", path, idx), warnings, unknown); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// sortedKeys returns the keys of mapping sorted by their textual form.
+func sortedKeys(mapping bindef.MapResult) []bindef.Result {
+	keys := make([]bindef.Result, 0, len(mapping))
+	for key := range mapping {
+		keys = append(keys, key)
+	}
+
+	slices.SortFunc(keys, func(a, b bindef.Result) int {
+		return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+	})
+
+	return keys
+}
+
+// formatKeys are the keys read by bindef from a format type, including those of
+// switch statements and of entries in the types section.
+var formatKeys = []string{
+	"id", "type", "name", "doc", "at", "valid", "magic", "if", "endian", "strip",
+	"fields", "item", "while", "members", "value", "switch", "cases", "default",
+}
+
+// memberKeys are the keys read by bindef from an enum member.
+var memberKeys = []string{"id", "value", "name", "doc"}
+
+// checkKeysIn calls [checkKeys] on every mapping in list.
+func checkKeysIn(list bindef.ListResult, path string, allowed []string, unknown *[]string) {
+	for idx, item := range list {
+		if mapping, ok := item.(bindef.MapResult); ok {
+			checkKeys(mapping, fmt.Sprintf("%s[%d]", path, idx), allowed, unknown)
+		}
+	}
+}
+
+// checkKeys reports the keys in mapping that are not in allowed. These are most
+// likely typos, as bindef silently ignores them.
+func checkKeys(mapping bindef.MapResult, path string, allowed []string, unknown *[]string) {
+	for _, key := range sortedKeys(mapping) {
+		if ident, ok := key.(bindef.IdentResult); ok && slices.Contains(allowed, string(ident)) {
+			continue
+		}
+
+		*unknown = append(*unknown, fmt.Sprintf("%s: unknown key %q", path, fmt.Sprint(key)))
+	}
+}
+
+// checkUnknown returns an error listing the unknown keys found in def, if any.
+func checkUnknown(def *Definition) error {
+	if len(def.Unknown) == 0 {
+		return nil
+	}
+
+	return errors.New(strings.Join(def.Unknown, "\n  "))
+}
+
+// evalKey returns the value of key in mapping, evaluating it without a namespace
+// if it is lazy. A nil result is returned if the key is missing or cannot be
+// evaluated without a namespace.
+func evalKey(mapping bindef.MapResult, key string) bindef.Result {
+	res, ok := mapping[bindef.IdentResult(key)]
+	if !ok {
+		return nil
+	}
+
+	if lazy, ok := res.(bindef.LazyResult); ok {
+		evalRes, err := lazy(nil)
+		if err != nil {
+			return nil
+		}
+		return evalRes
+	}
+
+	return res
+}
+
+func checkDuplicateIds(fields bindef.ListResult, path string) error {
+	seen := map[bindef.IdentResult]bool{}
+
+	for idx, field := range fields {
+		format, ok := field.(bindef.MapResult)
+		if !ok {
+			continue
+		}
+
+		if _, ok := format[bindef.IdentResult("if")]; ok {
+			continue
+		}
+
+		id, _ := evalKey(format, "id").(bindef.IdentResult)
+		if id == "" || strings.HasPrefix(string(id), "_") {
+			continue
+		}
+
+		if seen[id] {
+			return fmt.Errorf("%s[%d]: duplicate field id %q", path, idx, id)
+		}
+		seen[id] = true
+	}
+
+	return nil
+}
+
+// checkEnumMembers reports enum members sharing an id as an error. Members whose
+// values are equal or whose ranges overlap are reported as warnings, since only the
+// first of them is ever displayed.
+func checkEnumMembers(members bindef.ListResult, path string, warnings *[]string) error {
+	type span struct {
+		id       bindef.IdentResult
+		from, to *big.Int
+	}
+
+	seen := map[bindef.IdentResult]bool{}
+	spans := []span{}
+
+	for idx, member := range members {
+		element, ok := member.(bindef.MapResult)
+		if !ok {
+			continue
+		}
+
+		id, _ := evalKey(element, "id").(bindef.IdentResult)
+		if id != "" {
+			if seen[id] {
+				return fmt.Errorf("%s[%d]: duplicate enum member id %q", path, idx, id)
+			}
+			seen[id] = true
+		}
+
+		var current span
+		switch value := evalKey(element, "value").(type) {
+		case bindef.IntegerResult:
+			current = span{id: id, from: value.Int, to: value.Int}
+		case bindef.MapResult:
+			from, fromOk := value[bindef.IdentResult("from")].(bindef.IntegerResult)
+			to, toOk := value[bindef.IdentResult("to")].(bindef.IntegerResult)
+			if !fromOk || !toOk {
+				continue
+			}
+
+			current = span{id: id, from: from.Int, to: to.Int}
+		default:
+			continue
+		}
+
+		for _, prev := range spans {
+			if current.from.Cmp(prev.to) <= 0 && prev.from.Cmp(current.to) <= 0 {
+				*warnings = append(*warnings, fmt.Sprintf(
+					"%s[%d]: value of enum member %q overlaps with %q", path, idx, id, prev.id,
+				))
+				break
+			}
+		}
+
+		spans = append(spans, current)
+	}
+
+	return nil
+}
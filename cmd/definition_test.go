@@ -0,0 +1,710 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aescarias/bindef/bindef"
+)
+
+// compileDef compiles the BDF source in src, failing the test if it is invalid.
+func compileDef(t *testing.T, src string) *Definition {
+	t.Helper()
+
+	def, err := CompileDef([]byte(src))
+	if err != nil {
+		t.Fatalf("compile failed: %s", err)
+	}
+
+	return def
+}
+
+// writeFile creates a file called name with the specified contents in dir and
+// returns its path.
+func writeFile(t *testing.T, dir, name string, contents []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, contents, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// matchDef compiles the BDF source in src and applies it to a file holding data,
+// failing the test if either step fails.
+func matchDef(t *testing.T, src string, data []byte) []bindef.MetaPair {
+	t.Helper()
+
+	def := compileDef(t, src)
+	pairs, err := def.Match(writeFile(t, t.TempDir(), "input", data))
+	if err != nil {
+		t.Fatalf("match failed: %s", err)
+	}
+
+	return pairs
+}
+
+func TestCompileDef(t *testing.T) {
+	def := compileDef(t, `{
+		meta: { bdf: "0.5", name: "Test format", mime: ["application/x-test"] },
+		binary: [{ id: magic, type: byte[2], magic: _ == "TF" }]
+	}`)
+
+	if meta := def.Meta(); meta.Name != "Test format" || len(meta.Mime) != 1 {
+		t.Errorf("unexpected metadata: %+v", meta)
+	}
+
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"syntax", `{ meta: { bdf: "0.5" name: "x" }, binary: [] }`},
+		{"lexer", `{ meta: @ }`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileDef([]byte(tt.src))
+			if _, ok := err.(bindef.LangError); !ok {
+				t.Errorf("expected a language error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNewDefinition(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr bool
+	}{
+		{"valid", `{ meta: { bdf: "0.5", name: "Valid", exts: [".v"] }, binary: [] }`, false},
+		{"missing name", `{ meta: { bdf: "0.5" }, binary: [] }`, true},
+		{"missing meta", `{ binary: [] }`, true},
+		{"bad version", `{ meta: { bdf: "five", name: "Bad" }, binary: [] }`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def, err := CompileDef([]byte(tt.src))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got definition %+v", def.Meta())
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if meta := def.Meta(); meta.Name != "Valid" || meta.Version != (bindef.Version{Major: 0, Minor: 5}) {
+				t.Errorf("unexpected metadata: %+v", meta)
+			}
+		})
+	}
+}
+
+func TestCheckDuplicateIds(t *testing.T) {
+	tests := []struct {
+		name    string
+		binary  string
+		wantErr string
+	}{
+		{"unique", `{ id: a, type: uint8 }, { id: b, type: uint8 }`, ""},
+		{"duplicate", `{ id: a, type: uint8 }, { id: a, type: uint16 }`, `binary[1]: duplicate field id "a"`},
+		{"throwaway", `{ id: _, type: uint8 }, { id: _, type: uint8 }`, ""},
+		{"guarded", `{ id: a, type: uint8, if: true }, { id: a, type: uint16 }`, ""},
+		{
+			"nested duplicate",
+			`{ id: s, type: struct, fields: [{ id: a, type: uint8 }, { id: a, type: uint8 }] }`,
+			`binary[0].fields[1]: duplicate field id "a"`,
+		},
+		{
+			"separate scopes",
+			`{ id: a, type: uint8 }, { id: s, type: struct, fields: [{ id: a, type: uint8 }] }`,
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `{ meta: { bdf: "0.5", name: "Test" }, binary: [` + tt.binary + `] }`
+
+			_, err := CompileDef([]byte(src))
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			} else if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckEnumMembers(t *testing.T) {
+	tests := []struct {
+		name     string
+		members  string
+		wantErr  string
+		warnings []string
+	}{
+		{"distinct", `{ id: a, value: 1 }, { id: b, value: { from: 2, to: 4 } }`, "", nil},
+		{
+			"duplicate id",
+			`{ id: a, value: 1 }, { id: a, value: 2 }`,
+			`binary[0].members[1]: duplicate enum member id "a"`,
+			nil,
+		},
+		{
+			"equal values",
+			`{ id: a, value: 1 }, { id: b, value: 1 }`,
+			"",
+			[]string{`binary[0].members[1]: value of enum member "b" overlaps with "a"`},
+		},
+		{
+			"overlapping ranges",
+			`{ id: a, value: { from: 1, to: 4 } }, { id: b, value: { from: 4, to: 8 } }`,
+			"",
+			[]string{`binary[0].members[1]: value of enum member "b" overlaps with "a"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := `{ meta: { bdf: "0.5", name: "Test" }, binary: [
+				{ id: kind, type: enum[uint8], members: [` + tt.members + `] }
+			] }`
+
+			def, err := CompileDef([]byte(src))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if !slices.Equal(def.Warnings, tt.warnings) {
+				t.Errorf("expected warnings %q, got %q", tt.warnings, def.Warnings)
+			}
+		})
+	}
+}
+
+func TestRecoverMalformed(t *testing.T) {
+	t.Run("load", func(t *testing.T) {
+		tests := []string{
+			`{ meta: { bdf: "0.5", name: 5 }, binary: [] }`,
+			`{ meta: { bdf: 5, name: "Test" }, binary: [] }`,
+			`{ meta: { bdf: "0.5", name: "Test", mime: "a" }, binary: [] }`,
+		}
+
+		for _, src := range tests {
+			if _, err := CompileDef([]byte(src)); err == nil || !strings.HasPrefix(err.Error(), "malformed definition: ") {
+				t.Errorf("expected a malformed definition error for %s, got %v", src, err)
+			}
+		}
+	})
+
+	t.Run("match", func(t *testing.T) {
+		def := compileDef(t, `{ meta: { bdf: "0.5", name: "Test" }, binary: [{ id: a, type: uint16, endian: 5 }] }`)
+		input := writeFile(t, t.TempDir(), "input", []byte("abcd"))
+
+		if _, err := def.Match(input); err == nil || !strings.HasPrefix(err.Error(), "malformed definition: ") {
+			t.Errorf("expected a malformed definition error, got %v", err)
+		}
+	})
+}
+
+func TestMatchPartial(t *testing.T) {
+	src := `{ meta: { bdf: "0.5", name: "Test" }, binary: [
+		{ id: magic, type: byte[2], magic: _ == "PT" },
+		{ id: a, type: uint8 },
+		{ id: b, type: uint8, valid: b == 0 }
+	] }`
+
+	dir := t.TempDir()
+	input := writeFile(t, dir, "input", []byte("PT\x01\x02"))
+
+	t.Run("disabled", func(t *testing.T) {
+		def := compileDef(t, src)
+
+		pairs, err := def.Match(input)
+		if _, ok := err.(ErrPartial); ok || err == nil || pairs != nil {
+			t.Errorf("expected a plain error and no pairs, got %v, %v", pairs, err)
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		def := compileDef(t, src)
+		def.AllowPartial()
+		def.SetVar("unused", bindef.StringResult("x"))
+
+		pairs, err := def.Match(input)
+
+		perr, ok := err.(ErrPartial)
+		if !ok {
+			t.Fatalf("expected an ErrPartial, got %v", err)
+		}
+
+		if idx, _ := failedField(perr.Err); idx != 2 {
+			t.Errorf("expected the error to name binary[2], got %q", perr.Err)
+		}
+
+		if len(pairs) != 2 || pairs[0].Field.Id != "magic" || pairs[1].Field.Id != "a" {
+			t.Errorf("expected the magic and a fields, got %v", pairs)
+		}
+	})
+
+	t.Run("magic mismatch", func(t *testing.T) {
+		def := compileDef(t, src)
+		def.AllowPartial()
+
+		other := writeFile(t, dir, "other", []byte("XX\x01\x02"))
+		if _, err := def.Match(other); !errors.As(err, new(bindef.ErrMagic)) {
+			t.Errorf("expected ErrMagic, got %v", err)
+		}
+	})
+
+	t.Run("first field", func(t *testing.T) {
+		def := compileDef(t, `{ meta: { bdf: "0.5", name: "Test" }, binary: [
+			{ id: a, type: uint8, valid: a == 0 }
+		] }`)
+		def.AllowPartial()
+
+		if _, err := def.Match(input); err == nil || errors.As(err, new(ErrPartial)) {
+			t.Errorf("expected a plain error, got %v", err)
+		}
+	})
+
+	t.Run("match", func(t *testing.T) {
+		def := compileDef(t, src)
+		def.AllowPartial()
+
+		match, err := MatchDefinition("test.bdf", def, input)
+		if err != nil || match.Partial == nil || len(match.Pairs) != 2 {
+			t.Errorf("expected a partial match, got %+v, %v", match, err)
+		}
+	})
+}
+
+func TestRankMatches(t *testing.T) {
+	match := func(name, format string, priority int, supersedes ...string) Match {
+		def := &Definition{Priority: priority, Supersedes: supersedes, meta: bindef.Meta{Name: format}}
+		return Match{Name: name, Def: def}
+	}
+
+	partial := func(name, format string, priority int) Match {
+		m := match(name, format, priority)
+		m.Partial = errors.New("binary[1]: failed")
+		return m
+	}
+
+	tests := []struct {
+		name    string
+		matches []Match
+		want    []string
+	}{
+		{"by name", []Match{match("b.bdf", "B", 0), match("a.bdf", "A", 0)}, []string{"a.bdf", "b.bdf"}},
+		{"by priority", []Match{match("a.bdf", "A", -1), match("b.bdf", "B", 3)}, []string{"b.bdf", "a.bdf"}},
+		{
+			"extreme priorities",
+			[]Match{match("a.bdf", "A", math.MinInt), match("b.bdf", "B", math.MaxInt), match("c.bdf", "C", -1)},
+			[]string{"b.bdf", "c.bdf", "a.bdf"},
+		},
+		{
+			"superseded",
+			[]Match{match("riff.bdf", "RIFF", 5), match("wave.bdf", "WAVE", 0, "RIFF")},
+			[]string{"wave.bdf"},
+		},
+		{
+			"partial after complete",
+			[]Match{partial("a.bdf", "A", 5), match("b.bdf", "B", 0), partial("c.bdf", "C", 7)},
+			[]string{"b.bdf", "c.bdf", "a.bdf"},
+		},
+		{
+			"mutually superseded",
+			[]Match{match("b.bdf", "B", 0, "A"), match("a.bdf", "A", 0, "B")},
+			[]string{"a.bdf"},
+		},
+		{
+			"mutually superseded by priority",
+			[]Match{match("a.bdf", "A", 0, "B"), match("b.bdf", "B", 1, "A")},
+			[]string{"b.bdf"},
+		},
+		{
+			"supersedes itself",
+			[]Match{match("a.bdf", "A", 0, "A"), match("b.bdf", "B", 0)},
+			[]string{"a.bdf", "b.bdf"},
+		},
+		{
+			"superseded by removed match",
+			[]Match{match("a.bdf", "A", 2, "B"), match("b.bdf", "B", 1, "C"), match("c.bdf", "C", 0)},
+			[]string{"a.bdf", "c.bdf"},
+		},
+		{
+			"superseded format absent",
+			[]Match{match("wave.bdf", "WAVE", 0, "RIFF"), match("png.bdf", "PNG", 0)},
+			[]string{"png.bdf", "wave.bdf"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, match := range RankMatches(tt.matches) {
+				got = append(got, match.Name)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRanking(t *testing.T) {
+	def := compileDef(t, `{ meta: { bdf: "0.5", name: "Ranked", priority: 4, supersedes: ["Generic"] }, binary: [] }`)
+	if def.Priority != 4 || !slices.Equal(def.Supersedes, []string{"Generic"}) {
+		t.Errorf("got priority %d and supersedes %q", def.Priority, def.Supersedes)
+	}
+
+	tests := []string{
+		`{ meta: { bdf: "0.5", name: "Bad", priority: "high" }, binary: [] }`,
+		`{ meta: { bdf: "0.5", name: "Bad", supersedes: "Generic" }, binary: [] }`,
+		`{ meta: { bdf: "0.5", name: "Bad", supersedes: [1] }, binary: [] }`,
+	}
+
+	for _, src := range tests {
+		if _, err := CompileDef([]byte(src)); err == nil || !strings.HasPrefix(err.Error(), "meta: ") {
+			t.Errorf("expected a meta error for %s, got %v", src, err)
+		}
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	spec := bindef.SpecVersion
+
+	tests := []struct {
+		version bindef.Version
+		wantErr bool
+	}{
+		{spec, false},
+		{bindef.Version{Major: spec.Major, Minor: spec.Minor + 1}, true},
+		{bindef.Version{Major: spec.Major + 1}, true},
+		{bindef.Version{Major: 0, Minor: 1}, false},
+	}
+
+	for _, tt := range tests {
+		if err := checkVersion(bindef.Meta{Version: tt.version}); (err != nil) != tt.wantErr {
+			t.Errorf("checkVersion(%s) = %v, want error: %t", tt.version, err, tt.wantErr)
+		}
+	}
+
+	def := compileDef(t, `{ meta: { bdf: "99.0", name: "Future" }, binary: [] }`)
+	if len(def.Warnings) != 1 || !strings.HasPrefix(def.Warnings[0], "requires BDF 99.0") {
+		t.Errorf("expected a version warning, got %q", def.Warnings)
+	}
+}
+
+func TestMatchAll(t *testing.T) {
+	defs := map[string]*Definition{
+		"low.bdf": compileDef(t, `{ meta: { bdf: "0.5", name: "Low" }, binary: [
+			{ id: magic, type: byte[2], magic: _ == "MA" }
+		] }`),
+		"high.bdf": compileDef(t, `{ meta: { bdf: "0.5", name: "High", priority: 1 }, binary: [
+			{ id: magic, type: byte[2], magic: _ == "MA" },
+			{ id: value, type: uint8 }
+		] }`),
+		"other.bdf": compileDef(t, `{ meta: { bdf: "0.5", name: "Other" }, binary: [
+			{ id: magic, type: byte[2], magic: _ == "XX" }
+		] }`),
+		"failing.bdf": compileDef(t, `{ meta: { bdf: "0.5", name: "Failing" }, binary: [
+			{ id: value, type: uint8, valid: value == 0 }
+		] }`),
+	}
+
+	input := writeFile(t, t.TempDir(), "input", []byte("MA\x07"))
+	timings := map[string]time.Duration{}
+
+	matches, failed := MatchAll(defs, input, timings)
+
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got %d", len(matches))
+	}
+
+	for idx, want := range []string{"high.bdf", "low.bdf"} {
+		if match := matches[idx]; match.Name != want || match.Def != defs[want] {
+			t.Errorf("matches[%d] is %s, want %s", idx, match.Name, want)
+		}
+	}
+
+	if high := matches[0]; high.Meta().Name != "High" || len(high.Pairs) != 2 {
+		t.Errorf("unexpected match for high.bdf: %+v", high)
+	}
+
+	if keys := slices.Sorted(maps.Keys(failed)); !slices.Equal(keys, []string{"failing.bdf"}) {
+		t.Errorf("expected only failing.bdf to fail, got %q", keys)
+	}
+
+	if keys := slices.Sorted(maps.Keys(timings)); len(keys) != len(defs) {
+		t.Errorf("expected a timing for every definition, got %q", keys)
+	}
+}
+
+func TestSetVar(t *testing.T) {
+	src := `{ meta: { bdf: "0.5", name: "Test" }, binary: [
+		{ id: magic, type: byte[2], magic: _ == "SV" },
+		{ id: size, type: uint8, valid: size == expected },
+		{ id: label, type: var, value: tag }
+	] }`
+
+	dir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		data     string
+		expected int64
+		wantErr  string
+	}{
+		{name: "valid", data: "SV\x07", expected: 7},
+		{name: "invalid", data: "SV\x08", expected: 7, wantErr: "binary[1]:"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			def := compileDef(t, src)
+			def.SetVar("expected", parseVar(fmt.Sprint(test.expected)))
+			def.SetVar("tag", parseVar("hello"))
+
+			pairs, err := def.Match(writeFile(t, dir, test.name, []byte(test.data)))
+
+			if test.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), test.wantErr) {
+					t.Errorf("expected an error starting with %q, got %v", test.wantErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("match failed: %s", err)
+			}
+
+			ids := []string{}
+			for _, pair := range pairs {
+				ids = append(ids, pair.Field.Id)
+			}
+
+			if want := []string{"magic", "size", "label"}; !slices.Equal(ids, want) {
+				t.Errorf("expected fields %q, got %q", want, ids)
+			}
+		})
+	}
+}
+
+func TestUnshiftError(t *testing.T) {
+	def := compileDef(t, `{ meta: { bdf: "0.5", name: "Test" }, binary: [] }`)
+	def.SetVar("a", bindef.StringResult("a"))
+	def.SetVar("b", bindef.StringResult("b"))
+
+	inner := errors.New("failed")
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "shifted", err: fmt.Errorf("binary[3]: %w", inner), want: "binary[1]: failed"},
+		{name: "variable", err: fmt.Errorf("binary[1]: %w", inner), want: "binary[1]: failed"},
+		{name: "no index", err: inner, want: "failed"},
+		{name: "not wrapped", err: errors.New("binary[3]: failed"), want: "binary[3]: failed"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := def.unshiftError(test.err)
+			if got.Error() != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+
+			if errors.Is(test.err, inner) && !errors.Is(got, inner) {
+				t.Errorf("expected %q to wrap the original error", got)
+			}
+		})
+	}
+}
+
+func TestMagicPrefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		types  string
+		binary string
+		want   int
+	}{
+		{
+			name:   "no magic",
+			binary: `{ id: a, type: uint8 }, { id: b, type: uint8 }`,
+			want:   2,
+		},
+		{
+			name:   "leading magic",
+			binary: `{ id: sig, type: byte[2], magic: _ == "MZ" }, { id: a, type: uint8 }, { id: b, type: uint8 }`,
+			want:   1,
+		},
+		{
+			name: "nested magic",
+			binary: `{ id: head, type: struct, fields: [{ id: sig, type: byte[2], magic: _ == "MZ" }] },
+				{ id: a, type: uint8 }`,
+			want: 1,
+		},
+		{
+			name:  "type reference",
+			types: `{ id: trailer, type: byte[2], magic: _ == "ZZ" }`,
+			binary: `{ id: sig, type: byte[2], magic: _ == "MZ" }, { id: pad, type: uint8 },
+				{ id: tr, type: trailer }, { id: a, type: uint8 }`,
+			want: 3,
+		},
+		{
+			name: "nested type reference",
+			types: `{ id: trailer, type: struct, fields: [{ id: end, type: byte[2], magic: _ == "ZZ" }] },
+				{ id: wrapper, type: struct, fields: [{ id: inner, type: trailer }] }`,
+			binary: `{ id: sig, type: byte[2], magic: _ == "MZ" }, { id: w, type: wrapper }, { id: a, type: uint8 }`,
+			want:   2,
+		},
+		{
+			name:   "reference without magic",
+			types:  `{ id: point, type: struct, fields: [{ id: x, type: uint8 }, { id: y, type: uint8 }] }`,
+			binary: `{ id: sig, type: byte[2], magic: _ == "MZ" }, { id: p, type: point }`,
+			want:   1,
+		},
+		{
+			name:  "recursive reference",
+			types: `{ id: node, type: struct, fields: [{ id: next, type: node }] }`,
+			binary: `{ id: sig, type: byte[2], magic: _ == "MZ" }, { id: n, type: node, if: false },
+				{ id: a, type: uint8 }`,
+			want: 1,
+		},
+		{
+			name:  "switch case",
+			types: `{ id: trailer, type: byte[2], magic: _ == "ZZ" }`,
+			binary: `{ id: sig, type: byte[2], magic: _ == "MZ" }, { id: kind, type: uint8 },
+				{ id: body, switch: kind, cases: { 1: { type: trailer } }, default: { type: uint8 } },
+				{ id: a, type: uint8 }`,
+			want: 3,
+		},
+		{
+			name: "switch case reference",
+			types: `{ id: trailer, type: struct, endian: "little",
+				fields: [{ id: end, type: byte[2], magic: _ == "ZZ" }] }`,
+			binary: `{ id: sig, type: byte[2], magic: _ == "MZ" }, { id: kind, type: uint8 },
+				{ id: body, switch: kind, cases: { 1: trailer }, default: { type: uint8 } },
+				{ id: a, type: uint8 }`,
+			want: 3,
+		},
+		{
+			name:  "unresolved reference",
+			types: `{ id: trailer, type: byte[2], magic: _ == "ZZ" }`,
+			binary: `{ id: sig, type: byte[2], magic: _ == "MZ" }, { id: kind, type: uint8 },
+				{ id: tr, type: kind == 1 ? trailer : byte[2] }, { id: a, type: uint8 }`,
+			want: 3,
+		},
+		{
+			name:  "unresolved without magic types",
+			types: `{ id: point, type: struct, fields: [{ id: x, type: uint8 }] }`,
+			binary: `{ id: sig, type: byte[2], magic: _ == "MZ" }, { id: size, type: uint8 },
+				{ id: data, type: byte[size] }`,
+			want: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			def := compileDef(t, `{ meta: { bdf: "0.5", name: "Test" }, types: [`+test.types+`], binary: [`+test.binary+`] }`)
+
+			document := def.Document.(bindef.MapResult)
+			binary := document[bindef.IdentResult("binary")].(bindef.ListResult)
+			types := document[bindef.IdentResult("types")].(bindef.ListResult)
+
+			if got := magicPrefix(binary, types); got != test.want {
+				t.Errorf("magicPrefix() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSkipExtraction(t *testing.T) {
+	// the magic value is asserted by a field of the trailer type, as bindef does
+	// not apply a magic key given on the type itself to the fields using it.
+	src := `{ meta: { bdf: "0.5", name: "Test" },
+		types: [{ id: trailer, type: struct, endian: "little", fields: [{ id: end, type: byte[2], magic: _ == "ZZ" }] }],
+		binary: [
+			{ id: sig, type: byte[2], magic: _ == "MZ" },
+			{ id: pad, type: uint8 },
+			{ id: tr, type: trailer },
+			{ id: rest, type: uint8 }
+		] }`
+
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		data    string
+		wantIds []string
+	}{
+		{name: "match", data: "MZ\x00ZZ\x01", wantIds: []string{"sig", "pad", "tr"}},
+		{name: "trailer mismatch", data: "MZ\x00XX\x01"},
+		{name: "signature mismatch", data: "XX\x00ZZ\x01"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := writeFile(t, dir, test.name, []byte(test.data))
+
+			def := compileDef(t, src)
+			def.SkipExtraction()
+
+			pairs, err := def.Match(input)
+
+			if test.wantIds == nil {
+				if !errors.As(err, new(bindef.ErrMagic)) {
+					t.Errorf("expected ErrMagic, got %v", err)
+				}
+
+				if _, fullErr := compileDef(t, src).Match(input); !errors.As(fullErr, new(bindef.ErrMagic)) {
+					t.Errorf("expected ErrMagic without SkipExtraction, got %v", fullErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("match failed: %s", err)
+			}
+
+			ids := []string{}
+			for _, pair := range pairs {
+				ids = append(ids, pair.Field.Id)
+			}
+
+			if !slices.Equal(ids, test.wantIds) {
+				t.Errorf("expected fields %q, got %q", test.wantIds, ids)
+			}
+		})
+	}
+}
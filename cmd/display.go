@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aescarias/bindef/bindef"
+)
+
+// FormatResult renders res in a form suitable for display. Integers are shown in
+// decimal, strings are quoted and lists and maps are bracketed with their items
+// formatted recursively. Map items are sorted by key.
+func FormatResult(res bindef.Result) string {
+	switch r := res.(type) {
+	case nil:
+		return "<nil>"
+	case bindef.IntegerResult:
+		if r.Int == nil {
+			return "0"
+		}
+		return r.String()
+	case bindef.FloatResult:
+		return strconv.FormatFloat(float64(r), 'g', -1, 64)
+	case bindef.BooleanResult:
+		return strconv.FormatBool(bool(r))
+	case bindef.StringResult:
+		return strconv.Quote(string(r))
+	case bindef.IdentResult:
+		return string(r)
+	case bindef.ListResult:
+		items := make([]string, len(r))
+		for idx, item := range r {
+			items[idx] = FormatResult(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case bindef.MapResult:
+		items := make([]string, 0, len(r))
+		for key, value := range r {
+			items = append(items, FormatResult(key)+": "+FormatResult(value))
+		}
+		slices.Sort(items)
+		return "{" + strings.Join(items, ", ") + "}"
+	case bindef.LazyResult:
+		return "<lazy>"
+	case bindef.TypeResult:
+		if len(r.Params) == 0 {
+			return string(r.Name)
+		}
+
+		params := make([]string, len(r.Params))
+		for idx, param := range r.Params {
+			params[idx] = FormatResult(param)
+		}
+		return string(r.Name) + "[" + strings.Join(params, ", ") + "]"
+	default:
+		return fmt.Sprintf("%v", res)
+	}
+}
+
+// A ColorMode determines whether output is colorized.
+type ColorMode string
+
+const (
+	ColorAuto   ColorMode = "auto"   // Colorize if stdout is a terminal and NO_COLOR is empty or unset.
+	ColorAlways ColorMode = "always" // Always colorize.
+	ColorNever  ColorMode = "never"  // Never colorize.
+)
+
+// A Styler applies ANSI styles to text if it is enabled. The zero value leaves text
+// unchanged.
+type Styler struct {
+	Enabled bool
+}
+
+// NewStyler returns a styler that is enabled according to mode.
+func NewStyler(mode ColorMode) Styler {
+	return Styler{Enabled: useColor(mode, isTerminal(os.Stdout))}
+}
+
+// useColor reports whether output should be colorized according to mode, given
+// whether stdout is a terminal.
+func useColor(mode ColorMode, terminal bool) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+
+	// per the NO_COLOR convention, an empty value does not disable colors.
+	return terminal && os.Getenv("NO_COLOR") == ""
+}
+
+// isTerminal reports whether file refers to a terminal.
+func isTerminal(file *os.File) bool {
+	stat, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+func (s Styler) apply(code string, text string) string {
+	if !s.Enabled {
+		return text
+	}
+	return "\x1b[" + code + "m" + text + "\x1b[0m"
+}
+
+// Header styles a section header such as '== match'.
+func (s Styler) Header(text string) string { return s.apply("1", text) }
+
+// Key styles the name of a field or property.
+func (s Styler) Key(text string) string { return s.apply("36", text) }
+
+// Value styles the value of a field or property.
+func (s Styler) Value(text string) string { return s.apply("32", text) }
+
+// Error styles an error message.
+func (s Styler) Error(text string) string { return s.apply("31", text) }
+
+// Doc styles documentation shown alongside a field.
+func (s Styler) Doc(text string) string { return s.apply("2", text) }
+
+// DisplayOptions controls how extracted metadata is printed.
+type DisplayOptions struct {
+	FullBytes bool   // Whether to show byte sequences in full rather than truncated.
+	MaxBytes  int    // Number of bytes shown of a truncated byte sequence.
+	Style     Styler // Styling applied to field names and values.
+	Describe  bool   // Whether to show the documentation of fields and enum members.
+}
+
+// ShowField prints a pair containing a format type and a value with the specified
+// indent level. Spaces are used for indentation.
+func ShowField(pair bindef.MetaPair, indent int, opts DisplayOptions) {
+	indentStr := strings.Repeat("  ", indent)
+	style := opts.Style
+
+	var key string
+	if pair.Field.Name != "" {
+		key = pair.Field.Name
+	} else {
+		key = pair.Field.Id
+		if strings.HasPrefix(key, "_") || key == "" {
+			return
+		}
+	}
+
+	docs := []string{}
+	if opts.Describe && pair.Field.Doc != "" {
+		docs = append(docs, pair.Field.Doc)
+	}
+
+	// describe formats the documentation collected for the field as a trailing
+	// comment, if there is any to show.
+	describe := func() string {
+		if len(docs) == 0 {
+			return ""
+		}
+		return "  " + style.Doc("// "+strings.Join(docs, "; "))
+	}
+
+	switch f := pair.Field; f.Type {
+	case bindef.TypeByte:
+		str := string(pair.Value.(bindef.StringResult))
+
+		if cutoff := opts.MaxBytes; !opts.FullBytes && len(str) > cutoff {
+			str = fmt.Sprintf("%q", str[:cutoff]) + fmt.Sprintf(" (%d bytes remain)", len(str)-cutoff)
+		} else {
+			str = fmt.Sprintf("%q", str)
+		}
+
+		fmt.Printf("%s%s: %s%s\n", indentStr, style.Key(key), style.Value(str), describe())
+	case bindef.TypeStruct:
+		mapping := pair.Value.(bindef.MapResult)
+
+		fmt.Printf("%s%s:%s\n", indentStr, style.Key(key), describe())
+		for _, field := range f.ProcFields {
+			id := bindef.IdentResult(field.Id)
+			if id == "" {
+				continue
+			}
+
+			ShowField(bindef.MetaPair{Field: field, Value: mapping[id]}, indent+1, opts)
+		}
+	case bindef.TypeArray:
+		list := pair.Value.(bindef.ListResult)
+		fmt.Printf("%s%s (%d):%s\n", indentStr, style.Key(key), len(list), describe())
+
+		for idx, field := range f.ProcArrItems {
+			ShowField(bindef.MetaPair{Field: field, Value: list[idx]}, indent+1, opts)
+		}
+	case bindef.TypeEnum:
+		var friendlyName string
+		if member, ok := findEnumMember(f.EnumMembers, pair.Value); ok {
+			if opts.Describe {
+				// the member's documentation is shown separately, so
+				// the identifier is used as its name.
+				friendlyName = member.Id
+				if member.Doc != "" {
+					docs = append(docs, member.Doc)
+				}
+			} else if member.Doc != "" {
+				friendlyName = member.Doc
+			} else {
+				friendlyName = member.Id
+			}
+		}
+
+		value := fmt.Sprintf("%s (%#x)", friendlyName, pair.Value)
+		fmt.Printf("%s%s: %s%s\n", indentStr, style.Key(key), style.Value(value), describe())
+	default:
+		fmt.Printf("%s%s: %s%s\n", indentStr, style.Key(key), style.Value(FormatResult(pair.Value)), describe())
+	}
+}
+
+// findEnumMember returns the first member of members that value belongs to, either
+// by being equal to the member's value or by falling within its range.
+func findEnumMember(members []bindef.EnumMember, value bindef.Result) (bindef.EnumMember, bool) {
+	intValue, ok := value.(bindef.IntegerResult)
+	if !ok {
+		return bindef.EnumMember{}, false
+	}
+
+	for _, member := range members {
+		switch memberValue := member.Value.(type) {
+		case bindef.IntegerResult:
+			if intValue.Cmp(memberValue.Int) == 0 {
+				return member, true
+			}
+		case bindef.MapResult:
+			from, fromOk := memberValue[bindef.IdentResult("from")].(bindef.IntegerResult)
+			to, toOk := memberValue[bindef.IdentResult("to")].(bindef.IntegerResult)
+			if fromOk && toOk && intValue.Cmp(from.Int) >= 0 && intValue.Cmp(to.Int) <= 0 {
+				return member, true
+			}
+		}
+	}
+
+	return bindef.EnumMember{}, false
+}
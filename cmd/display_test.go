@@ -0,0 +1,172 @@
+package main
+
+import (
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aescarias/bindef/bindef"
+)
+
+func TestFormatResult(t *testing.T) {
+	integer := func(v int64) bindef.IntegerResult {
+		return bindef.IntegerResult{Int: big.NewInt(v)}
+	}
+
+	tests := []struct {
+		name string
+		res  bindef.Result
+		want string
+	}{
+		{"nil", nil, "<nil>"},
+		{"integer", integer(-42), "-42"},
+		{"zero integer", bindef.IntegerResult{}, "0"},
+		{"float", bindef.FloatResult(1.5), "1.5"},
+		{"boolean", bindef.BooleanResult(true), "true"},
+		{"string", bindef.StringResult("a\x00b"), `"a\x00b"`},
+		{"ident", bindef.IdentResult("little"), "little"},
+		{"list", bindef.ListResult{integer(1), bindef.StringResult("x")}, `[1, "x"]`},
+		{
+			"map",
+			bindef.MapResult{bindef.IdentResult("b"): integer(2), bindef.IdentResult("a"): integer(1)},
+			"{a: 1, b: 2}",
+		},
+		{"lazy", bindef.LazyResult(nil), "<lazy>"},
+		{"type", bindef.TypeResult{Name: "byte", Params: []bindef.Result{integer(4)}}, "byte[4]"},
+		{"bare type", bindef.TypeResult{Name: "uint8"}, "uint8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatResult(tt.res); got != tt.want {
+				t.Errorf("FormatResult() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// captureStdout returns what fn prints to stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	return captureFile(t, &os.Stdout, fn)
+}
+
+// captureFile returns what fn writes to the file pointed to by file, such as
+// os.Stdout or os.Stderr.
+func captureFile(t *testing.T, file **os.File, fn func()) string {
+	t.Helper()
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	original := *file
+	*file = writer
+	defer func() { *file = original }()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		output <- string(data)
+	}()
+
+	fn()
+
+	writer.Close()
+	return <-output
+}
+
+func TestShowFieldMaxBytes(t *testing.T) {
+	pairs := matchDef(t, `{ meta: { bdf: "0.5", name: "Test" }, binary: [
+		{ id: data, type: byte[8] }
+	] }`, []byte("abcdefgh"))
+
+	tests := []struct {
+		name string
+		opts DisplayOptions
+		want string
+	}{
+		{"cut off", DisplayOptions{MaxBytes: 3}, `data: "abc" (5 bytes remain)`},
+		{"within limit", DisplayOptions{MaxBytes: 8}, `data: "abcdefgh"`},
+		{"full bytes", DisplayOptions{MaxBytes: 3, FullBytes: true}, `data: "abcdefgh"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := captureStdout(t, func() { ShowField(pairs[0], 0, tt.opts) })
+			if got = strings.TrimSuffix(got, "\n"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	tests := []struct {
+		mode     ColorMode
+		terminal bool
+		noColor  string
+		want     bool
+	}{
+		{ColorAuto, true, "", true},
+		{ColorAuto, true, "1", false},
+		{ColorAuto, false, "", false},
+		{ColorAlways, false, "1", true},
+		{ColorNever, true, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("NO_COLOR", tt.noColor)
+		if got := useColor(tt.mode, tt.terminal); got != tt.want {
+			t.Errorf("useColor(%s, %t) with NO_COLOR=%q = %t, want %t", tt.mode, tt.terminal, tt.noColor, got, tt.want)
+		}
+	}
+}
+
+func TestStyler(t *testing.T) {
+	if got := (Styler{}).Key("name"); got != "name" {
+		t.Errorf("disabled styler changed text: %q", got)
+	}
+
+	if got := (Styler{Enabled: true}).Error("failed"); got != "\x1b[31mfailed\x1b[0m" {
+		t.Errorf("unexpected styled text: %q", got)
+	}
+}
+
+func TestShowFieldDescribe(t *testing.T) {
+	pairs := matchDef(t, `{ meta: { bdf: "0.5", name: "Test" }, binary: [
+		{ id: size, name: "Size", type: uint8, doc: "Size of the body" },
+		{ id: kind, type: enum[uint8], doc: "Kind of body", members: [{ id: one, value: 1, doc: "First kind" }] },
+		{ id: plain, type: uint8 }
+	] }`, []byte{4, 1, 0})
+
+	tests := []struct {
+		name     string
+		describe bool
+		want     string
+	}{
+		{"plain", false, "Size: 4\nkind: First kind (0x1)\nplain: 0\n"},
+		{
+			"describe",
+			true,
+			"Size: 4  // Size of the body\nkind: one (0x1)  // Kind of body; First kind\nplain: 0\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := captureStdout(t, func() {
+				for _, pair := range pairs {
+					ShowField(pair, 0, DisplayOptions{MaxBytes: 256, Describe: tt.describe})
+				}
+			})
+
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
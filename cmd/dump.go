@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aescarias/bindef/bindef"
+)
+
+// DumpDefinition prints the metadata of def and a tree of the fields declared in its
+// 'binary' section without applying it to a file. Values that can only be known
+// once a file is read are shown as "<lazy>".
+func DumpDefinition(def *Definition) {
+	meta := def.Meta()
+
+	fmt.Println("name:", meta.Name)
+	fmt.Println("bdf version:", meta.Version)
+
+	root, ok := def.Document.(bindef.MapResult)
+	if !ok {
+		return
+	}
+
+	// a stub namespace resolving declared types, so that fields referring
+	// to them can be expanded.
+	ns := bindef.Namespace{bindef.IdentResult("eos"): bindef.IdentResult("eos")}
+	if types, ok := root[bindef.IdentResult("types")].(bindef.ListResult); ok {
+		for _, typeRes := range types {
+			if typeMap, ok := typeRes.(bindef.MapResult); ok {
+				if id, ok := evalKey(typeMap, "id").(bindef.IdentResult); ok {
+					ns[id] = typeMap
+				}
+			}
+		}
+	}
+
+	binary, _ := root[bindef.IdentResult("binary")].(bindef.ListResult)
+
+	fmt.Println("binary:")
+	for _, field := range binary {
+		dumpFormat(field, ns, 1)
+	}
+}
+
+// dumpFormat prints a single format type with the specified indent level followed
+// by any formats nested within it.
+func dumpFormat(res bindef.Result, ns bindef.Namespace, indent int) {
+	indentStr := strings.Repeat("  ", indent)
+
+	format, ok := res.(bindef.MapResult)
+	if !ok {
+		fmt.Printf("%s- %s\n", indentStr, FormatResult(res))
+		return
+	}
+
+	label := "(unnamed)"
+	if id, ok := evalKey(format, "id").(bindef.IdentResult); ok {
+		label = string(id)
+	}
+
+	if name, ok := format[bindef.IdentResult("name")].(bindef.StringResult); ok {
+		label += fmt.Sprintf(" %q", string(name))
+	}
+
+	typeRes := resolveStub(format[bindef.IdentResult("type")], ns)
+
+	var typeStr string
+	var declared bindef.MapResult
+	switch t := typeRes.(type) {
+	case nil:
+		typeStr = "(none)"
+	case bindef.MapResult:
+		// a reference to a type declared in 'types'.
+		declared = t
+		typeStr = "<lazy>"
+		if id, ok := evalKey(t, "id").(bindef.IdentResult); ok {
+			typeStr = string(id)
+		}
+	default:
+		typeStr = FormatResult(t)
+	}
+
+	var attrs []string
+	for _, key := range []string{"endian", "at"} {
+		if value, ok := format[bindef.IdentResult(key)]; ok {
+			attrs = append(attrs, key+"="+FormatResult(value))
+		}
+	}
+
+	for _, key := range []string{"if", "valid", "magic", "switch"} {
+		if _, ok := format[bindef.IdentResult(key)]; ok {
+			attrs = append(attrs, key)
+		}
+	}
+
+	line := fmt.Sprintf("%s- %s: %s", indentStr, label, typeStr)
+	if len(attrs) > 0 {
+		line += " [" + strings.Join(attrs, ", ") + "]"
+	}
+	fmt.Println(line)
+
+	if declared != nil {
+		dumpNested(declared, typeRes, ns, indent+1)
+	}
+	dumpNested(format, typeRes, ns, indent+1)
+}
+
+// dumpNested prints the fields, array item, enum members and switch cases of
+// format, if any.
+func dumpNested(format bindef.MapResult, typeRes bindef.Result, ns bindef.Namespace, indent int) {
+	indentStr := strings.Repeat("  ", indent)
+
+	if fields, ok := format[bindef.IdentResult("fields")].(bindef.ListResult); ok {
+		for _, field := range fields {
+			dumpFormat(field, ns, indent)
+		}
+	}
+
+	if item, ok := format[bindef.IdentResult("item")]; ok {
+		fmt.Printf("%sitem:\n", indentStr)
+		dumpFormat(item, ns, indent+1)
+	}
+
+	if members, ok := format[bindef.IdentResult("members")].(bindef.ListResult); ok {
+		for _, member := range members {
+			memberMap, ok := member.(bindef.MapResult)
+			if !ok {
+				continue
+			}
+
+			line := fmt.Sprintf("%s%s = %s", indentStr, FormatResult(evalKey(memberMap, "id")), FormatResult(evalKey(memberMap, "value")))
+			if doc, ok := memberMap[bindef.IdentResult("doc")].(bindef.StringResult); ok {
+				line += fmt.Sprintf(" (%s)", string(doc))
+			}
+			fmt.Println(line)
+		}
+	}
+
+	if cases, ok := format[bindef.IdentResult("cases")].(bindef.MapResult); ok {
+		keys := make([]bindef.Result, 0, len(cases))
+		for key := range cases {
+			keys = append(keys, key)
+		}
+
+		slices.SortFunc(keys, func(a, b bindef.Result) int {
+			return strings.Compare(FormatResult(a), FormatResult(b))
+		})
+
+		for _, key := range keys {
+			fmt.Printf("%scase %s:\n", indentStr, FormatResult(key))
+			dumpFormat(resolveStub(cases[key], ns), ns, indent+1)
+		}
+
+		if def, ok := format[bindef.IdentResult("default")]; ok {
+			fmt.Printf("%sdefault:\n", indentStr)
+			dumpFormat(resolveStub(def, ns), ns, indent+1)
+		}
+	}
+}
+
+// resolveStub evaluates res with the stub namespace ns if it is lazy. If it cannot
+// be evaluated without reading a file, res is returned as is.
+func resolveStub(res bindef.Result, ns bindef.Namespace) bindef.Result {
+	lazy, ok := res.(bindef.LazyResult)
+	if !ok {
+		return res
+	}
+
+	evalRes, err := lazy(ns)
+	if err != nil {
+		return res
+	}
+
+	return evalRes
+}
@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestDumpDefinition(t *testing.T) {
+	def := compileDef(t, `{
+		meta: { bdf: "0.5", name: "Dumped" },
+		types: [{ id: point, type: struct, fields: [{ id: x, type: uint16 }, { id: y, type: uint16 }] }],
+		binary: [
+			{ id: sig, type: byte[2], magic: _ == "DP" },
+			{ id: size, name: "Size", type: uint32, endian: "little" },
+			{ id: origin, type: point },
+			{ id: kind, type: enum[uint8], members: [{ id: one, value: 1, doc: "First" }, { id: many, value: { from: 2, to: 9 } }] },
+			{ id: body, switch: kind, cases: { 1: { type: uint8 } }, default: { type: uint16 } },
+			{ id: rest, type: array[eos], item: { type: uint8 } }
+		]
+	}`)
+
+	want := `name: Dumped
+bdf version: 0.5
+binary:
+  - sig: byte[2] [magic]
+  - size "Size": uint32 [endian="little"]
+  - origin: point
+    - x: uint16
+    - y: uint16
+  - kind: enum[uint8]
+    one = 1 (First)
+    many = {from: 2, to: 9}
+  - body: (none) [switch]
+    case 1:
+      - (unnamed): uint8
+    default:
+      - (unnamed): uint16
+  - rest: array[eos]
+    item:
+      - (unnamed): uint8
+`
+
+	if got := captureStdout(t, func() { DumpDefinition(def) }); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
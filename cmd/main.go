@@ -1,75 +1,158 @@
 package main
 
 import (
+	"cmp"
+	"embed"
 	"errors"
 	"fmt"
 	"io/fs"
+	"maps"
+	"math/big"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/aescarias/bindef/bindef"
 )
 
 var VERSION = "0.6.0"
 
-func ParseDef(filepath string) bindef.Result {
-	bdfData, err := os.ReadFile(filepath)
-	if slices.Equal(bdfData, []byte{}) {
-		fmt.Printf("found empty bdf at %s\n", filepath)
-		os.Exit(1)
-	}
+// embeddedFS holds the definitions bundled into the executable at build time. See
+// formats/README.md.
+//
+//go:embed formats
+var embeddedFS embed.FS
+
+// embeddedDefs is the file system that embedded definitions are loaded from, under
+// the "formats" folder.
+var embeddedDefs fs.FS = embeddedFS
+
+// ErrDefinition describes a definition file at Path that could not be loaded.
+// Source holds the contents of the file, if it could be read.
+type ErrDefinition struct {
+	Path   string
+	Source []byte
+	Err    error
+}
 
-	if err != nil {
+func (e ErrDefinition) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Err)
+}
+
+func (e ErrDefinition) Unwrap() error {
+	return e.Err
+}
+
+// ReportDefError prints an error produced while loading a definition. Language
+// errors are reported with the offending source line.
+func ReportDefError(err error) {
+	var derr ErrDefinition
+	if !errors.As(err, &derr) {
 		fmt.Println(err)
-		os.Exit(1)
+		return
 	}
 
-	lex := bindef.NewLexer(bdfData)
+	if _, ok := derr.Err.(bindef.LangError); ok {
+		bindef.ReportError(derr.Path, derr.Source, derr.Err)
+	} else {
+		fmt.Printf("%s:\n  %s\n", derr.Path, derr.Err)
+	}
+}
 
-	if err := lex.Process(); err != nil {
-		bindef.ReportError(filepath, bdfData, err)
-		os.Exit(1)
+func ParseDef(filepath string) (*Definition, error) {
+	bdfData, err := os.ReadFile(filepath)
+	if err != nil {
+		return nil, ErrDefinition{Path: filepath, Err: err}
 	}
 
-	ps := bindef.NewParser(lex.Tokens)
+	return parseDefSource(filepath, bdfData)
+}
 
-	tree, err := ps.Parse()
-	if err != nil {
-		bindef.ReportError(filepath, bdfData, err)
-		os.Exit(1)
+func parseDefSource(filepath string, bdfData []byte) (*Definition, error) {
+	if len(bdfData) == 0 {
+		return nil, ErrDefinition{Path: filepath, Err: errors.New("definition is empty")}
 	}
 
-	result, err := bindef.Evaluate(tree, nil)
+	def, err := CompileDef(bdfData)
 	if err != nil {
-		bindef.ReportError(filepath, bdfData, err)
-		os.Exit(1)
+		return nil, ErrDefinition{Path: filepath, Source: bdfData, Err: err}
 	}
 
-	return result
+	def.Path = filepath
+	return def, nil
+}
+
+// LoadDefinitionsFS loads every definition found under root in fsys, keyed by
+// file name. Definitions that fail to load are returned as invalid rather than
+// stopping the walk.
+func LoadDefinitionsFS(fsys fs.FS, root string) (map[string]*Definition, []error, error) {
+	return loadDefsFS(fsys, root, func(path string) string { return path })
 }
 
-func GetDefs(path string) (map[string]bindef.Result, error) {
-	defs := map[string]bindef.Result{}
+// loadDefsFS implements [LoadDefinitionsFS]. The name function maps a path in
+// fsys to the path reported for a definition that fails to load.
+func loadDefsFS(fsys fs.FS, root string, name func(path string) string) (defs map[string]*Definition, invalid []error, err error) {
+	defs = map[string]*Definition{}
 
-	err := filepath.Walk(path, func(path string, info fs.FileInfo, err error) error {
+	err = fs.WalkDir(fsys, root, func(path string, entry fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
-		if !info.IsDir() && strings.HasSuffix(info.Name(), ".bdf") {
-			defs[info.Name()] = ParseDef(path)
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".bdf") {
+			bdfData, err := fs.ReadFile(fsys, path)
+			if err != nil {
+				invalid = append(invalid, ErrDefinition{Path: name(path), Err: err})
+				return nil
+			}
+
+			def, err := parseDefSource(name(path), bdfData)
+			if err != nil {
+				invalid = append(invalid, err)
+				return nil
+			}
+
+			defs[entry.Name()] = def
 		}
 
 		return nil
 	})
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return defs, nil
+	return defs, invalid, nil
+}
+
+// GetDefs loads every definition found under path, which may be a folder or a
+// single definition file. Definitions that fail to load are returned as invalid.
+func GetDefs(path string) (defs map[string]*Definition, invalid []error, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// a single file is loaded from its parent folder, as the root of a file
+	// system cannot be a file.
+	dir, root := path, "."
+	if !info.IsDir() {
+		dir, root = filepath.Dir(path), filepath.Base(path)
+	}
+
+	return loadDefsFS(os.DirFS(dir), root, func(name string) string {
+		return filepath.Join(dir, filepath.FromSlash(name))
+	})
+}
+
+// GetEmbeddedDefs loads the definitions bundled into the executable. Definitions
+// that fail to load are returned as invalid.
+func GetEmbeddedDefs() (defs map[string]*Definition, invalid []error, err error) {
+	return loadDefsFS(embeddedDefs, "formats", func(path string) string {
+		return "embedded:" + path
+	})
 }
 
 func GetDefaultDefsPaths() (exec string, cwd string, err error) {
@@ -96,146 +179,548 @@ func (e ErrLookupFailed) Error() string {
 	return fmt.Sprintf("failed to load definitions from %d location(s)", len(e.Issues))
 }
 
-func LoadDefs(paths []string) (map[string]bindef.Result, error) {
+// LoadDefinitions loads the definitions from every path in paths and merges them.
+// Definitions in later paths replace those with the same file name in earlier ones.
+// Errors for individual definition files are collected in invalid.
+//
+// If any path cannot be walked, an [ErrLookupFailed] describing it is returned. The
+// definitions loaded from the remaining paths are still returned, so callers should
+// only treat the error as fatal if no definitions were returned.
+func LoadDefinitions(paths ...string) (map[string]*Definition, []error, error) {
+	var defs map[string]*Definition
+	var invalid []error
+
 	lookupErrors := map[string]error{}
 	for _, path := range paths {
-		defs, err := GetDefs(path)
+		pathDefs, pathInvalid, err := GetDefs(path)
 		if err != nil {
 			lookupErrors[path] = err
 			continue
 		}
-		return defs, nil
+
+		if defs == nil {
+			defs = map[string]*Definition{}
+		}
+
+		maps.Copy(defs, pathDefs)
+		invalid = append(invalid, pathInvalid...)
 	}
 
-	return nil, ErrLookupFailed{Issues: lookupErrors}
+	if len(lookupErrors) > 0 {
+		return defs, invalid, ErrLookupFailed{Issues: lookupErrors}
+	}
+
+	return defs, invalid, nil
 }
 
-func main() {
-	args := ParseCmdArgs(os.Args[1:])
+// LoadDefaultDefinitions loads the definitions from every path in paths like
+// [LoadDefinitions]. If no definitions could be loaded from any of them, the
+// embedded definitions are returned instead, if there are any, and embedded is
+// set. The error from loading paths is returned in either case.
+func LoadDefaultDefinitions(paths ...string) (defs map[string]*Definition, invalid []error, embedded bool, err error) {
+	defs, invalid, err = LoadDefinitions(paths...)
+	if defs != nil {
+		return defs, invalid, false, err
+	}
 
-	if args.ShowHelp {
-		ShowHelp()
-		os.Exit(0)
+	bundled, bundledInvalid, bundledErr := GetEmbeddedDefs()
+	if bundledErr != nil || len(bundled)+len(bundledInvalid) == 0 {
+		return defs, invalid, false, err
 	}
 
-	if args.ShowVersion {
-		fmt.Println("BinID version", VERSION)
-		os.Exit(0)
+	return bundled, bundledInvalid, true, err
+}
+
+// ReportLookupFailed prints the reason each location in lerr could not be loaded.
+func ReportLookupFailed(lerr ErrLookupFailed) {
+	fmt.Println(lerr)
+	for _, path := range slices.Sorted(maps.Keys(lerr.Issues)) {
+		if err := lerr.Issues[path]; errors.Is(err, fs.ErrNotExist) {
+			fmt.Printf("%s:\n  the path does not exist\n", path)
+		} else {
+			fmt.Printf("%s:\n  %s\n", path, err)
+		}
+	}
+}
+
+// rejectDefinitions removes the definitions in defs for which reject returns an
+// error and returns those errors, in order of definition name.
+func rejectDefinitions(defs map[string]*Definition, reject func(def *Definition) error) []error {
+	var rejected []error
+	for _, name := range slices.Sorted(maps.Keys(defs)) {
+		if err := reject(defs[name]); err != nil {
+			rejected = append(rejected, ErrDefinition{Path: defs[name].Path, Err: err})
+			delete(defs, name)
+		}
 	}
 
-	handle, err := os.Open(args.Filename)
+	return rejected
+}
+
+// parseVar converts the value of a variable given in the command line to a result.
+// Values that are valid integers become numbers and anything else a string.
+func parseVar(value string) bindef.Result {
+	if num, ok := new(big.Int).SetString(value, 0); ok {
+		return bindef.IntegerResult{Int: num}
+	}
+
+	return bindef.StringResult(value)
+}
+
+// BestMatch returns the best definition in defs that matches filename, as ranked
+// by [RankMatches]. If requireMime is set, definitions that do not declare a MIME
+// type are skipped.
+func BestMatch(defs map[string]*Definition, filename string, requireMime bool) (*Definition, bool) {
+	if requireMime {
+		defs = maps.Clone(defs)
+		maps.DeleteFunc(defs, func(_ string, def *Definition) bool {
+			return len(def.Meta().Mime) == 0
+		})
+	}
+
+	if matches, _ := MatchAll(defs, filename, nil); len(matches) > 0 {
+		return matches[0].Def, true
+	}
+
+	return nil, false
+}
+
+// ShowBestMatch prints the name and/or MIME type of the best definition matching
+// filename, one per line, and reports whether a match was found. If prefix is set,
+// each line is prefixed with the filename. If filename cannot be opened, the error
+// is printed to stderr instead.
+func ShowBestMatch(defs map[string]*Definition, filename string, args CmdArgs, prefix bool) bool {
+	handle, err := os.Open(filename)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		fmt.Fprintln(os.Stderr, err)
+		return false
 	}
-	defer handle.Close()
+	handle.Close()
 
-	var lookupPaths []string
-	if args.DefsPath != "" {
-		lookupPaths = []string{args.DefsPath}
-	} else {
-		exePath, cwdPath, err := GetDefaultDefsPaths()
-		if err != nil {
-			fmt.Printf("failed definition lookup: %s\n", err)
-			os.Exit(1)
+	def, found := BestMatch(defs, filename, args.OnlyMime)
+	if !found {
+		return false
+	}
+
+	meta := def.Meta()
+
+	var lines []string
+	if args.OnlyName {
+		lines = append(lines, meta.Name)
+	}
+
+	if args.OnlyMime {
+		lines = append(lines, meta.Mime[0])
+	}
+
+	for _, line := range lines {
+		if prefix {
+			fmt.Printf("%s: %s\n", filename, line)
+		} else {
+			fmt.Println(line)
 		}
-		lookupPaths = []string{exePath, cwdPath}
 	}
 
-	defs, err := LoadDefs(lookupPaths)
-	if err != nil {
-		if lerr, ok := err.(ErrLookupFailed); ok {
-			fmt.Println(lerr)
-			for path, err := range lerr.Issues {
-				if errors.Is(err, fs.ErrNotExist) {
-					fmt.Printf("%s:\n  the path does not exist\n", path)
-				} else {
-					fmt.Printf("%s:\n  %s\n", path, err)
-				}
+	return true
+}
+
+// walkFiles calls fn for every regular file under root, recording in progress
+// whether fn reported a match. Entries that cannot be read are reported with a
+// warning on stderr and skipped. It reports false if any entry was skipped this
+// way.
+//
+// Symbolic links within root are skipped with a warning on stderr unless follow
+// is set.
+// Directories are only visited once, so that links forming a loop are not
+// followed endlessly.
+func walkFiles(root string, follow bool, progress *Progress, fn func(path string) bool) bool {
+	ok := true
+	visited := map[string]bool{}
+
+	warn := func(err error) {
+		progress.Clear()
+		fmt.Fprintf(os.Stderr, "warning: %s\n", err)
+		progress.Fail()
+		ok = false
+	}
+
+	var visit func(path string, mode fs.FileMode)
+	visit = func(path string, mode fs.FileMode) {
+		if mode&fs.ModeSymlink != 0 {
+			if !follow {
+				progress.Clear()
+				fmt.Fprintf(os.Stderr, "warning: skipping symbolic link %s\n", path)
+				return
 			}
+
+			stat, err := os.Stat(path)
+			if err != nil {
+				warn(err)
+				return
+			}
+			mode = stat.Mode().Type()
+		}
+
+		switch {
+		case mode.IsDir():
+			realPath, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				warn(err)
+				return
+			}
+
+			if visited[realPath] {
+				progress.Clear()
+				fmt.Fprintf(os.Stderr, "warning: skipping %s, already visited as %s\n", path, realPath)
+				return
+			}
+			visited[realPath] = true
+
+			entries, err := os.ReadDir(path)
+			if err != nil {
+				warn(err)
+				return
+			}
+
+			for _, entry := range entries {
+				visit(filepath.Join(path, entry.Name()), entry.Type())
+			}
+		case mode.IsRegular():
+			progress.Clear()
+
+			handle, err := os.Open(path)
+			if err != nil {
+				warn(err)
+				return
+			}
+			handle.Close()
+
+			progress.File(fn(path))
 		}
-		os.Exit(1)
 	}
 
-	fmt.Printf("found %d definition(s)\n", len(defs))
-	if len(defs) <= 0 {
-		os.Exit(1)
+	// the root itself is always followed, as it was named explicitly.
+	stat, err := os.Stat(root)
+	if err != nil {
+		warn(err)
+		return ok
+	}
+
+	visit(root, stat.Mode().Type())
+	return ok
+}
+
+// ScanDir identifies every regular file under root and prints a one-line summary
+// of the best match for each. It reports false if any file could not be read.
+func ScanDir(defs map[string]*Definition, root string, follow bool, progress *Progress) bool {
+	return walkFiles(root, follow, progress, func(path string) bool {
+		def, found := BestMatch(defs, path, false)
+		if !found {
+			fmt.Printf("%s: no match\n", path)
+			return false
+		}
+
+		if meta := def.Meta(); len(meta.Mime) > 0 {
+			fmt.Printf("%s: %s (%s)\n", path, meta.Name, meta.Mime[0])
+		} else {
+			fmt.Printf("%s: %s\n", path, meta.Name)
+		}
+
+		return true
+	})
+}
+
+// isDir reports whether path refers to a directory.
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// ShowTimings prints how long each definition took to match, slowest first.
+func ShowTimings(timings map[string]time.Duration, style Styler) {
+	names := slices.Collect(maps.Keys(timings))
+	slices.SortFunc(names, func(a, b string) int {
+		if c := cmp.Compare(timings[b], timings[a]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+
+	var total time.Duration
+
+	fmt.Println()
+	fmt.Println(style.Header("== benchmark"))
+	for _, name := range names {
+		fmt.Printf("%s: %s\n", style.Key(name), timings[name])
+		total += timings[name]
 	}
+	fmt.Printf("%s: %s\n", style.Key("total"), total)
+}
 
-	inputStat, err := os.Stat(args.Filename)
+// IdentifyFile matches filename against every definition in defs and prints the
+// results. It reports false if the file could not be read.
+func IdentifyFile(defs map[string]*Definition, filename string, args CmdArgs) bool {
+	handle, err := os.Open(filename)
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		return false
+	}
+	defer handle.Close()
+
+	inputStat, err := handle.Stat()
+	if err != nil {
+		fmt.Println(err)
+		return false
 	}
 
 	if inputStat.IsDir() {
-		fmt.Printf("%s is a directory\n", args.Filename)
-		os.Exit(0)
+		fmt.Printf("%s is a directory\n", filename)
+		return true
 	}
 
 	if inputStat.Size() <= 0 {
-		fmt.Printf("%s is empty\n", args.Filename)
-		os.Exit(0)
+		fmt.Printf("%s is empty\n", filename)
+		return true
 	}
 
-	fmt.Printf("matching %s\n", args.Filename)
+	fmt.Printf("matching %s\n", filename)
 
-	found := false
-	failedMatches := map[string]error{}
+	style := NewStyler(args.Color)
+	displayOpts := DisplayOptions{
+		FullBytes: args.ShowAll,
+		MaxBytes:  args.MaxBytes,
+		Style:     style,
+		Describe:  args.Describe,
+	}
 
-	for defPath, defResult := range defs {
-		match, err := bindef.ApplyBDF(defResult, args.Filename)
-		if err != nil {
-			if _, ok := err.(bindef.ErrMagic); !ok {
-				failedMatches[defPath] = err
-			}
-			continue
-		}
+	timings := map[string]time.Duration{}
+	matches, failedMatches := MatchAll(defs, filename, timings)
 
-		meta, err := bindef.GetMetadata(defResult)
-		if err != nil {
-			failedMatches[defPath] = fmt.Errorf("metadata get failed: %w", err)
-			continue
-		}
+	for _, match := range matches {
+		meta := match.Meta()
 
-		found = true
 		fmt.Println()
-		fmt.Println("== match")
-		fmt.Println("name:", meta.Name)
+		fmt.Println(style.Header("== match"))
+		fmt.Println(style.Key("name:"), meta.Name)
 
 		if len(meta.Mime) > 0 {
-			fmt.Println("mime(s):", strings.Join(meta.Mime, ", "))
+			fmt.Println(style.Key("mime(s):"), strings.Join(meta.Mime, ", "))
 		}
 
 		if len(meta.Exts) > 0 {
-			fmt.Println("extension(s):", strings.Join(meta.Exts, ", "))
+			fmt.Println(style.Key("extension(s):"), strings.Join(meta.Exts, ", "))
 		}
 
 		if meta.Doc != "" {
-			fmt.Println("details:", meta.Doc)
+			fmt.Println(style.Key("details:"), meta.Doc)
+		}
+
+		if args.NoExtract {
+			continue
 		}
 
 		fmt.Println()
-		fmt.Println("== metadata")
+		fmt.Println(style.Header("== metadata"))
 
-		if len(match) <= 0 {
+		if len(match.Pairs) <= 0 {
 			fmt.Println("no metadata extracted")
-			continue
 		}
 
-		for _, pair := range match {
-			bindef.ShowMetadataField(pair, 0, args.ShowAll)
+		for _, pair := range match.Pairs {
+			ShowField(pair, 0, displayOpts)
+		}
+
+		if match.Partial != nil {
+			fmt.Println(style.Error("extraction stopped early: " + match.Partial.Error()))
 		}
 	}
 
 	if len(failedMatches) > 0 {
-		fmt.Println("\n== errors")
-		for defPath, err := range failedMatches {
-			fmt.Printf("%s:\n  %s\n", defPath, err)
+		fmt.Println()
+		fmt.Println(style.Header("== errors"))
+		for _, defPath := range slices.Sorted(maps.Keys(failedMatches)) {
+			err := failedMatches[defPath]
+			fmt.Printf("%s:\n  %s\n", defPath, style.Error(err.Error()))
 		}
 	}
 
-	if !found {
+	if args.Benchmark {
+		ShowTimings(timings, style)
+	}
+
+	if len(matches) == 0 {
 		fmt.Println("no definitions matched")
 	}
+
+	return true
+}
+
+// IdentifyFiles identifies each of the files given in args, scanning directories
+// instead if recursion is enabled, and returns the exit status: 1 if any of them
+// could not be read, otherwise 0.
+func IdentifyFiles(defs map[string]*Definition, args CmdArgs) int {
+	status := 0
+
+	for idx, filename := range args.Filenames {
+		if idx > 0 {
+			fmt.Println()
+		}
+
+		if args.Recurse && isDir(filename) {
+			progress := NewProgress(args.Progress)
+			if !ScanDir(defs, filename, args.FollowLinks, progress) {
+				status = 1
+			}
+			progress.Done()
+		} else if !IdentifyFile(defs, filename, args) {
+			status = 1
+		}
+	}
+
+	return status
+}
+
+func main() {
+	args := ParseCmdArgs(os.Args[1:])
+
+	if args.ShowHelp {
+		ShowHelp()
+		os.Exit(0)
+	}
+
+	if args.ShowVersion {
+		fmt.Println("BinID version", VERSION)
+		os.Exit(0)
+	}
+
+	if args.Command == "dump" {
+		status := 0
+		for idx, filename := range args.Filenames {
+			if idx > 0 {
+				fmt.Println()
+			}
+
+			def, err := ParseDef(filename)
+			if err != nil {
+				ReportDefError(err)
+				status = 1
+				continue
+			}
+
+			DumpDefinition(def)
+		}
+		os.Exit(status)
+	}
+
+	lookupPaths := args.DefsPaths
+	if len(lookupPaths) == 0 {
+		exePath, cwdPath, err := GetDefaultDefsPaths()
+		if err != nil {
+			fmt.Printf("failed definition lookup: %s\n", err)
+			os.Exit(1)
+		}
+		lookupPaths = []string{exePath}
+		if cwdPath != exePath {
+			lookupPaths = append(lookupPaths, cwdPath)
+		}
+	}
+
+	var defs map[string]*Definition
+	var invalid []error
+	var usingEmbedded bool
+	var err error
+
+	if len(args.DefsPaths) > 0 {
+		defs, invalid, err = LoadDefinitions(lookupPaths...)
+	} else {
+		defs, invalid, usingEmbedded, err = LoadDefaultDefinitions(lookupPaths...)
+	}
+	lerr, lookupFailed := err.(ErrLookupFailed)
+
+	if defs == nil {
+		if lookupFailed {
+			ReportLookupFailed(lerr)
+		}
+		os.Exit(1)
+	}
+
+	if args.CheckVer {
+		invalid = append(invalid, rejectDefinitions(defs, func(def *Definition) error {
+			return checkVersion(def.Meta())
+		})...)
+	}
+
+	if args.Strict {
+		invalid = append(invalid, rejectDefinitions(defs, checkUnknown)...)
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(args.Vars)) {
+		value := parseVar(args.Vars[key])
+		for _, def := range defs {
+			def.SetVar(key, value)
+		}
+	}
+
+	for _, def := range defs {
+		if args.NoExtract {
+			def.SkipExtraction()
+		}
+
+		if args.BestEffort {
+			def.AllowPartial()
+		}
+	}
+
+	status := 0
+
+	if args.OnlyName || args.OnlyMime {
+		for _, filename := range args.Filenames {
+			if args.Recurse && isDir(filename) {
+				progress := NewProgress(args.Progress)
+				if !walkFiles(filename, args.FollowLinks, progress, func(path string) bool {
+					if !ShowBestMatch(defs, path, args, true) {
+						status = 1
+						return false
+					}
+					return true
+				}) {
+					status = 1
+				}
+				progress.Done()
+			} else if !ShowBestMatch(defs, filename, args, len(args.Filenames) > 1 || args.Recurse) {
+				status = 1
+			}
+		}
+		os.Exit(status)
+	}
+
+	// the default locations are only fallbacks for each other, so one of them
+	// missing is not worth reporting.
+	if lookupFailed && len(args.DefsPaths) > 0 {
+		ReportLookupFailed(lerr)
+	}
+
+	if len(invalid) > 0 {
+		fmt.Printf("failed to load %d definition(s)\n", len(invalid))
+		for _, err := range invalid {
+			ReportDefError(err)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(defs)) {
+		for _, warning := range defs[name].Warnings {
+			fmt.Printf("warning: %s: %s\n", name, warning)
+		}
+	}
+
+	if usingEmbedded {
+		fmt.Println("no definitions folder found, using embedded definitions")
+	}
+
+	fmt.Printf("found %d definition(s)\n", len(defs))
+	if len(defs) <= 0 {
+		os.Exit(1)
+	}
+
+	os.Exit(IdentifyFiles(defs, args))
 }
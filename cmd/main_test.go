@@ -0,0 +1,635 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"maps"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/aescarias/bindef/bindef"
+)
+
+func TestParseDef(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := writeFile(t, dir, "valid.bdf", []byte(`{ meta: { bdf: "0.5", name: "Valid" }, binary: [] }`))
+	if def, err := ParseDef(valid); err != nil || def.Meta().Name != "Valid" {
+		t.Errorf("ParseDef(%q) = %v, %v", valid, def, err)
+	}
+
+	tests := []struct {
+		name     string
+		contents []byte
+	}{
+		{"empty.bdf", nil},
+		{"invalid.bdf", []byte(`{ meta: { bdf: "0.5" name: "x" } }`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, dir, tt.name, tt.contents)
+
+			var derr ErrDefinition
+			if _, err := ParseDef(path); !errors.As(err, &derr) || derr.Path != path {
+				t.Errorf("expected an ErrDefinition for %s, got %v", path, err)
+			}
+		})
+	}
+}
+
+func TestGetDefsCollectsInvalid(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.bdf", []byte(`{ meta: { bdf: "0.5", name: "A" }, binary: [] }`))
+	writeFile(t, dir, "nested/b.bdf", []byte(`{ meta: { bdf: "0.5", name: "B" }, binary: [] }`))
+	writeFile(t, dir, "empty.bdf", nil)
+	writeFile(t, dir, "broken.bdf", []byte(`{ meta: { bdf: "0.5" }, binary: [] }`))
+	writeFile(t, dir, "notes.txt", []byte("not a definition"))
+
+	defs, invalid, err := GetDefs(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(defs) != 2 || defs["a.bdf"] == nil || defs["b.bdf"] == nil {
+		t.Errorf("expected a.bdf and b.bdf to load, got %v", slices.Sorted(maps.Keys(defs)))
+	}
+
+	if len(invalid) != 2 {
+		t.Errorf("expected 2 invalid definitions, got %v", invalid)
+	}
+}
+
+// testDefs compiles a definition matching files starting with "TD" under the name
+// a.bdf, and one declaring a MIME type under the name b.bdf.
+func testDefs(t *testing.T) map[string]*Definition {
+	t.Helper()
+
+	return map[string]*Definition{
+		"a.bdf": compileDef(t, `{ meta: { bdf: "0.5", name: "Plain" }, binary: [
+			{ id: magic, type: byte[2], magic: _ == "TD" }
+		] }`),
+		"b.bdf": compileDef(t, `{ meta: { bdf: "0.5", name: "Typed", mime: ["application/x-td"] }, binary: [
+			{ id: magic, type: byte[2], magic: _ == "TD" }
+		] }`),
+	}
+}
+
+func TestBestMatch(t *testing.T) {
+	defs := testDefs(t)
+	dir := t.TempDir()
+	input := writeFile(t, dir, "input", []byte("TD"))
+
+	if def, ok := BestMatch(defs, input, false); !ok || def.Meta().Name != "Plain" {
+		t.Errorf("expected Plain to be the best match, got %v", def)
+	}
+
+	if def, ok := BestMatch(defs, input, true); !ok || def.Meta().Name != "Typed" {
+		t.Errorf("expected Typed to be the best match with a MIME type, got %v", def)
+	}
+
+	other := writeFile(t, dir, "other", []byte("XX"))
+	if def, ok := BestMatch(defs, other, false); ok {
+		t.Errorf("expected no match, got %s", def.Meta().Name)
+	}
+}
+
+func TestBestMatchPartial(t *testing.T) {
+	defs := map[string]*Definition{
+		"a.bdf": compileDef(t, `{ meta: { bdf: "0.5", name: "Partial", priority: 5 }, binary: [
+			{ id: magic, type: byte[2], magic: _ == "TD" },
+			{ id: value, type: uint8, valid: value == 0 }
+		] }`),
+		"b.bdf": compileDef(t, `{ meta: { bdf: "0.5", name: "Complete" }, binary: [
+			{ id: magic, type: byte[2], magic: _ == "TD" }
+		] }`),
+	}
+
+	for _, def := range defs {
+		def.AllowPartial()
+	}
+
+	input := writeFile(t, t.TempDir(), "input", []byte("TD\x01"))
+
+	if def, ok := BestMatch(defs, input, false); !ok || def.Meta().Name != "Complete" {
+		t.Errorf("expected the complete match to rank first, got %v", def)
+	}
+}
+
+func TestShowBestMatch(t *testing.T) {
+	defs := testDefs(t)
+	input := writeFile(t, t.TempDir(), "input", []byte("TD"))
+
+	tests := []struct {
+		args   CmdArgs
+		prefix bool
+		want   string
+	}{
+		{CmdArgs{OnlyName: true}, false, "Plain\n"},
+		{CmdArgs{OnlyMime: true}, false, "application/x-td\n"},
+		{CmdArgs{OnlyName: true, OnlyMime: true}, true, input + ": Typed\n" + input + ": application/x-td\n"},
+	}
+
+	for _, tt := range tests {
+		got := captureStdout(t, func() {
+			if !ShowBestMatch(defs, input, tt.args, tt.prefix) {
+				t.Error("expected a match")
+			}
+		})
+
+		if got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+
+	t.Run("no match", func(t *testing.T) {
+		other := writeFile(t, t.TempDir(), "other", []byte("XX"))
+
+		var found bool
+		var stdout string
+		stderr := captureFile(t, &os.Stderr, func() {
+			stdout = captureStdout(t, func() { found = ShowBestMatch(defs, other, CmdArgs{OnlyName: true}, false) })
+		})
+
+		if found || stdout != "" || stderr != "" {
+			t.Errorf("expected no output, got %q on stdout and %q on stderr", stdout, stderr)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		missing := filepath.Join(t.TempDir(), "missing")
+
+		var found bool
+		var stdout string
+		stderr := captureFile(t, &os.Stderr, func() {
+			stdout = captureStdout(t, func() { found = ShowBestMatch(defs, missing, CmdArgs{OnlyName: true}, false) })
+		})
+
+		if found || stdout != "" || !strings.Contains(stderr, missing) {
+			t.Errorf("expected the open error on stderr, got %q on stdout and %q on stderr", stdout, stderr)
+		}
+	})
+}
+
+func TestScanDir(t *testing.T) {
+	defs := testDefs(t)
+
+	dir := t.TempDir()
+	writeFile(t, dir, "a", []byte("TD"))
+	writeFile(t, dir, "sub/b", []byte("XX"))
+	writeFile(t, dir, "sub/deeper/c", []byte("TD"))
+
+	var ok bool
+	got := captureStdout(t, func() { ok = ScanDir(defs, dir, false, nil) })
+
+	want := strings.Join([]string{
+		filepath.Join(dir, "a") + ": Plain",
+		filepath.Join(dir, "sub", "b") + ": no match",
+		filepath.Join(dir, "sub", "deeper", "c") + ": Plain",
+	}, "\n") + "\n"
+
+	if !ok || got != want {
+		t.Errorf("ScanDir() = %t, printed:\n%s\nwant:\n%s", ok, got, want)
+	}
+}
+
+func TestLoadDefinitions(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	writeFile(t, first, "a.bdf", []byte(`{ meta: { bdf: "0.5", name: "First A" }, binary: [] }`))
+	writeFile(t, first, "b.bdf", []byte(`{ meta: { bdf: "0.5", name: "First B" }, binary: [] }`))
+	writeFile(t, second, "b.bdf", []byte(`{ meta: { bdf: "0.5", name: "Second B" }, binary: [] }`))
+	writeFile(t, second, "c.bdf", []byte(`{ meta: { bdf: "0.5" }, binary: [] }`))
+
+	missing := filepath.Join(first, "missing")
+
+	defs, invalid, err := LoadDefinitions(first, missing, second)
+
+	var lerr ErrLookupFailed
+	if !errors.As(err, &lerr) || len(lerr.Issues) != 1 || lerr.Issues[missing] == nil {
+		t.Errorf("expected a lookup error for %s, got %v", missing, err)
+	}
+
+	names := map[string]string{}
+	for key, def := range defs {
+		names[key] = def.Meta().Name
+	}
+
+	want := map[string]string{"a.bdf": "First A", "b.bdf": "Second B"}
+	if !maps.Equal(names, want) {
+		t.Errorf("got definitions %v, want %v", names, want)
+	}
+
+	if len(invalid) != 1 {
+		t.Errorf("expected c.bdf to be invalid, got %v", invalid)
+	}
+
+	if defs, _, _ := LoadDefinitions(missing); defs != nil {
+		t.Errorf("expected no definitions when every path fails, got %v", defs)
+	}
+}
+
+func TestLoadDefaultDefinitionsEmbedded(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "formats")
+
+	t.Run("none embedded", func(t *testing.T) {
+		original := embeddedDefs
+		t.Cleanup(func() { embeddedDefs = original })
+
+		// the folder as committed, holding no definitions.
+		embeddedDefs = fstest.MapFS{
+			"formats/README.md": {Data: []byte("# Embedded definitions")},
+		}
+
+		defs, _, embedded, err := LoadDefaultDefinitions(missing)
+		if defs != nil || embedded || err == nil {
+			t.Errorf("expected only a lookup error, got %v, %t, %v", defs, embedded, err)
+		}
+	})
+
+	t.Run("embedded", func(t *testing.T) {
+		original := embeddedDefs
+		t.Cleanup(func() { embeddedDefs = original })
+
+		embeddedDefs = fstest.MapFS{
+			"formats/README.md": {Data: []byte("# Embedded definitions")},
+			"formats/a.bdf":     {Data: []byte(`{ meta: { bdf: "0.5", name: "Embedded A" }, binary: [] }`)},
+			"formats/b.bdf":     {Data: []byte(`{ meta: { bdf: "0.5" }, binary: [] }`)},
+		}
+
+		defs, invalid, embedded, _ := LoadDefaultDefinitions(missing)
+		if !embedded || len(defs) != 1 || defs["a.bdf"].Meta().Name != "Embedded A" {
+			t.Fatalf("expected the embedded definitions, got %v, %t", defs, embedded)
+		}
+
+		if path := defs["a.bdf"].Path; path != "embedded:formats/a.bdf" {
+			t.Errorf("unexpected path %q", path)
+		}
+
+		var derr ErrDefinition
+		if len(invalid) != 1 || !errors.As(invalid[0], &derr) || derr.Path != "embedded:formats/b.bdf" {
+			t.Errorf("expected b.bdf to be invalid, got %v", invalid)
+		}
+
+		dir := t.TempDir()
+		writeFile(t, dir, "c.bdf", []byte(`{ meta: { bdf: "0.5", name: "On disk" }, binary: [] }`))
+		if defs, _, embedded, _ := LoadDefaultDefinitions(missing, dir); embedded || defs["c.bdf"] == nil {
+			t.Errorf("expected the definitions on disk to be preferred, got %v", defs)
+		}
+	})
+}
+
+func TestRejectDefinitions(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "current.bdf", []byte(`{ meta: { bdf: "0.5", name: "Current" }, binary: [] }`))
+	future := writeFile(t, dir, "future.bdf", []byte(`{ meta: { bdf: "99.0", name: "Future" }, binary: [] }`))
+
+	defs, _, err := GetDefs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rejected := rejectDefinitions(defs, func(def *Definition) error {
+		return checkVersion(def.Meta())
+	})
+
+	var derr ErrDefinition
+	if len(rejected) != 1 || !errors.As(rejected[0], &derr) || derr.Path != future {
+		t.Errorf("expected %s to be rejected, got %v", future, rejected)
+	}
+
+	if len(defs) != 1 || defs["current.bdf"] == nil {
+		t.Errorf("expected only current.bdf to remain, got %v", slices.Sorted(maps.Keys(defs)))
+	}
+}
+
+func TestShowTimings(t *testing.T) {
+	timings := map[string]time.Duration{
+		"a.bdf": 2 * time.Millisecond,
+		"b.bdf": 5 * time.Millisecond,
+		"c.bdf": 2 * time.Millisecond,
+	}
+
+	want := `
+== benchmark
+b.bdf: 5ms
+a.bdf: 2ms
+c.bdf: 2ms
+total: 9ms
+`
+
+	if got := captureStdout(t, func() { ShowTimings(timings, Styler{}) }); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestLoadDefinitionsFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"defs/a.bdf":        {Data: []byte(`{ meta: { bdf: "0.5", name: "A" }, binary: [] }`)},
+		"defs/nested/b.bdf": {Data: []byte(`{ meta: { bdf: "0.5", name: "B" }, binary: [] }`)},
+		"defs/broken.bdf":   {Data: []byte(`{ meta: { bdf: "0.5" }, binary: [] }`)},
+		"defs/notes.txt":    {Data: []byte("not a definition")},
+		"other/c.bdf":       {Data: []byte(`{ meta: { bdf: "0.5", name: "C" }, binary: [] }`)},
+	}
+
+	defs, invalid, err := LoadDefinitionsFS(fsys, "defs")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if keys := slices.Sorted(maps.Keys(defs)); !slices.Equal(keys, []string{"a.bdf", "b.bdf"}) {
+		t.Errorf("got definitions %q", keys)
+	}
+
+	if path := defs["b.bdf"].Path; path != "defs/nested/b.bdf" {
+		t.Errorf("unexpected path %q", path)
+	}
+
+	var derr ErrDefinition
+	if len(invalid) != 1 || !errors.As(invalid[0], &derr) || derr.Path != "defs/broken.bdf" {
+		t.Errorf("expected broken.bdf to be invalid, got %v", invalid)
+	}
+
+	if _, _, err := LoadDefinitionsFS(fsys, "missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a missing root to fail, got %v", err)
+	}
+}
+
+func TestGetDefsPaths(t *testing.T) {
+	dir := t.TempDir()
+	def := writeFile(t, dir, "a.bdf", []byte(`{ meta: { bdf: "0.5", name: "A" }, binary: [] }`))
+	writeFile(t, dir, "sub/deeper/empty.txt", nil)
+
+	t.Chdir(filepath.Join(dir, "sub"))
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"parent", "..", filepath.Join("..", "a.bdf")},
+		{"grandparent", filepath.Join("deeper", "..", ".."), filepath.Join("..", "a.bdf")},
+		{"absolute", dir, def},
+		{"trailing separator", dir + string(filepath.Separator), def},
+		{"single file", def, def},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defs, _, err := GetDefs(tt.path)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if def := defs["a.bdf"]; def == nil || def.Path != tt.want {
+				t.Errorf("expected a.bdf at %s, got %v", tt.want, defs)
+			}
+		})
+	}
+}
+
+func TestRejectUnknownKeys(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "clean.bdf", []byte(`{ meta: { bdf: "0.5", name: "Clean" }, binary: [] }`))
+	typo := writeFile(t, dir, "typo.bdf", []byte(`{ meta: { bdf: "0.5", name: "Typo" }, binary: [
+		{ id: value, type: uint8, vaild: value == 0 }
+	] }`))
+
+	defs, _, err := GetDefs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if unknown := defs["typo.bdf"].Unknown; len(unknown) != 1 || !strings.Contains(unknown[0], `"vaild"`) {
+		t.Errorf("expected an unknown key warning for vaild, got %q", unknown)
+	}
+
+	if !slices.Equal(defs["typo.bdf"].Warnings, defs["typo.bdf"].Unknown) {
+		t.Errorf("expected unknown keys to be reported as warnings, got %q", defs["typo.bdf"].Warnings)
+	}
+
+	rejected := rejectDefinitions(defs, checkUnknown)
+
+	var derr ErrDefinition
+	if len(rejected) != 1 || !errors.As(rejected[0], &derr) || derr.Path != typo {
+		t.Errorf("expected %s to be rejected, got %v", typo, rejected)
+	}
+
+	if len(defs) != 1 || defs["clean.bdf"] == nil {
+		t.Errorf("expected only clean.bdf to remain, got %v", slices.Sorted(maps.Keys(defs)))
+	}
+}
+
+func TestParseVar(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bindef.Result
+	}{
+		{value: "42", want: bindef.IntegerResult{Int: big.NewInt(42)}},
+		{value: "-7", want: bindef.IntegerResult{Int: big.NewInt(-7)}},
+		{value: "0x10", want: bindef.IntegerResult{Int: big.NewInt(16)}},
+		{value: "hello", want: bindef.StringResult("hello")},
+		{value: "12abc", want: bindef.StringResult("12abc")},
+		{value: "", want: bindef.StringResult("")},
+	}
+
+	for _, test := range tests {
+		got := parseVar(test.value)
+
+		if want, ok := test.want.(bindef.IntegerResult); ok {
+			if num, ok := got.(bindef.IntegerResult); !ok || num.Int.Cmp(want.Int) != 0 {
+				t.Errorf("parseVar(%q) = %#v, want %s", test.value, got, want.Int)
+			}
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("parseVar(%q) = %#v, want %#v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestWalkFilesSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "a", []byte("TD"))
+
+	link := filepath.Join(dir, "link")
+	loop := filepath.Join(dir, "sub", "loop")
+	if err := os.Symlink(file, link); err != nil {
+		t.Skipf("cannot create symbolic links: %s", err)
+	}
+
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink(dir, loop); err != nil {
+		t.Fatal(err)
+	}
+
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		follow    bool
+		wantFiles []string
+		wantOut   string
+	}{
+		{
+			name:      "skip",
+			follow:    false,
+			wantFiles: []string{file},
+			wantOut: "warning: skipping symbolic link " + link + "\n" +
+				"warning: skipping symbolic link " + loop + "\n",
+		},
+		{
+			name:      "follow",
+			follow:    true,
+			wantFiles: []string{file, link},
+			wantOut:   "warning: skipping " + loop + ", already visited as " + realDir + "\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var files []string
+			var ok bool
+
+			out := captureFile(t, &os.Stderr, func() {
+				ok = walkFiles(dir, test.follow, nil, func(path string) bool {
+					files = append(files, path)
+					return true
+				})
+			})
+
+			if !ok {
+				t.Error("expected no entries to fail")
+			}
+
+			if !slices.Equal(files, test.wantFiles) {
+				t.Errorf("visited %q, want %q", files, test.wantFiles)
+			}
+
+			if out != test.wantOut {
+				t.Errorf("got output:\n%s\nwant:\n%s", out, test.wantOut)
+			}
+		})
+	}
+}
+
+func TestIdentifyFileDeterministic(t *testing.T) {
+	defs := map[string]*Definition{}
+	for _, name := range []string{"d", "b", "e", "a", "c"} {
+		defs[name+".bdf"] = compileDef(t, `{ meta: { bdf: "0.5", name: "Match `+name+`" }, binary: [
+			{ id: magic, type: byte[2], magic: _ == "TD" }
+		] }`)
+		defs[name+"-fail.bdf"] = compileDef(t, `{ meta: { bdf: "0.5", name: "Fail `+name+`" }, binary: [
+			{ id: value, type: uint16, valid: value == 0 }
+		] }`)
+	}
+
+	input := writeFile(t, t.TempDir(), "input", []byte("TD"))
+	args := CmdArgs{Color: ColorNever, NoExtract: true}
+
+	first := captureStdout(t, func() { IdentifyFile(defs, input, args) })
+
+	var names, failed []string
+	for line := range strings.Lines(first) {
+		if name, ok := strings.CutPrefix(line, "name: "); ok {
+			names = append(names, strings.TrimSpace(name))
+		}
+		if name, ok := strings.CutSuffix(line, "-fail.bdf:\n"); ok {
+			failed = append(failed, name)
+		}
+	}
+
+	if want := []string{"Match a", "Match b", "Match c", "Match d", "Match e"}; !slices.Equal(names, want) {
+		t.Errorf("expected matches in order %q, got %q", want, names)
+	}
+
+	if want := []string{"a", "b", "c", "d", "e"}; !slices.Equal(failed, want) {
+		t.Errorf("expected errors in order %q, got %q", want, failed)
+	}
+
+	for range 20 {
+		if got := captureStdout(t, func() { IdentifyFile(defs, input, args) }); got != first {
+			t.Fatalf("output differs between runs:\n%s\nfirst run:\n%s", got, first)
+		}
+	}
+}
+
+func TestIdentifyFiles(t *testing.T) {
+	defs := testDefs(t)
+
+	dir := t.TempDir()
+	first := writeFile(t, dir, "first", []byte("TD"))
+	second := writeFile(t, dir, "second", []byte("XX"))
+	missing := filepath.Join(dir, "missing")
+
+	tests := []struct {
+		name       string
+		filenames  []string
+		wantStatus int
+	}{
+		{name: "readable", filenames: []string{first, second}, wantStatus: 0},
+		{name: "unreadable", filenames: []string{first, missing, second}, wantStatus: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args := CmdArgs{Filenames: test.filenames, Color: ColorNever}
+
+			var status int
+			out := captureStdout(t, func() { status = IdentifyFiles(defs, args) })
+
+			if status != test.wantStatus {
+				t.Errorf("IdentifyFiles() = %d, want %d", status, test.wantStatus)
+			}
+
+			for _, filename := range []string{first, second} {
+				if !strings.Contains(out, "matching "+filename+"\n") {
+					t.Errorf("expected a block for %s, got:\n%s", filename, out)
+				}
+			}
+		})
+	}
+}
+
+func TestScanDirUnreadable(t *testing.T) {
+	defs := testDefs(t)
+
+	dir := t.TempDir()
+	input := writeFile(t, dir, "a", []byte("TD"))
+
+	// a link to a missing file cannot be read once followed.
+	dangling := filepath.Join(dir, "dangling")
+	if err := os.Symlink(filepath.Join(dir, "missing"), dangling); err != nil {
+		t.Skipf("cannot create symbolic links: %s", err)
+	}
+
+	var ok bool
+	var stdout string
+	stderr := captureFile(t, &os.Stderr, func() {
+		stdout = captureStdout(t, func() { ok = ScanDir(defs, dir, true, nil) })
+	})
+
+	if ok {
+		t.Error("expected the scan to report a failure")
+	}
+
+	if want := input + ": Plain\n"; stdout != want {
+		t.Errorf("got %q on stdout, want %q", stdout, want)
+	}
+
+	if !strings.HasPrefix(stderr, "warning: ") || !strings.Contains(stderr, dangling) {
+		t.Errorf("expected a warning for %s on stderr, got %q", dangling, stderr)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// A Progress reports on stderr how many files a recursive scan has processed. A nil
+// progress reports nothing.
+type Progress struct {
+	Scanned int // Number of files identified so far.
+	Matched int // Number of files matched by a definition.
+	Failed  int // Number of entries that could not be read.
+
+	// inline is set if the report is redrawn in place rather than printed as a
+	// line per file, which is done when stderr is a terminal.
+	inline bool
+}
+
+// NewProgress returns a progress that reports if enabled is set, or nil otherwise.
+func NewProgress(enabled bool) *Progress {
+	if !enabled {
+		return nil
+	}
+
+	return &Progress{inline: isTerminal(os.Stderr)}
+}
+
+// Clear removes the report from the terminal so that other output can be printed
+// in its place. It is redrawn by the next call to [Progress.File] or [Progress.Fail].
+func (p *Progress) Clear() {
+	if p != nil && p.inline && p.Scanned+p.Failed > 0 {
+		fmt.Fprint(os.Stderr, "\r\x1b[K")
+	}
+}
+
+// File records a file as identified, and as matched if matched is set.
+func (p *Progress) File(matched bool) {
+	if p == nil {
+		return
+	}
+
+	p.Scanned++
+	if matched {
+		p.Matched++
+	}
+	p.report()
+}
+
+// Fail records an entry that could not be read.
+func (p *Progress) Fail() {
+	if p == nil {
+		return
+	}
+
+	p.Failed++
+	p.report()
+}
+
+// Done ends the report, leaving the final counts on stderr.
+func (p *Progress) Done() {
+	if p != nil && p.inline && p.Scanned+p.Failed > 0 {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+func (p *Progress) report() {
+	status := fmt.Sprintf("scanned %d file(s), %d matched, %d failed", p.Scanned, p.Matched, p.Failed)
+	if p.inline {
+		fmt.Fprint(os.Stderr, "\r\x1b[K"+status)
+	} else {
+		fmt.Fprintln(os.Stderr, status)
+	}
+}
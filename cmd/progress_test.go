@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestProgress(t *testing.T) {
+	defs := testDefs(t)
+
+	dir := t.TempDir()
+	writeFile(t, dir, "a", []byte("TD"))
+	writeFile(t, dir, "sub/b", []byte("XX"))
+	writeFile(t, dir, "sub/c", []byte("TD"))
+
+	progress := &Progress{}
+
+	var stdout string
+	stderr := captureFile(t, &os.Stderr, func() {
+		stdout = captureStdout(t, func() { ScanDir(defs, dir, false, progress) })
+	})
+
+	if progress.Scanned != 3 || progress.Matched != 2 || progress.Failed != 0 {
+		t.Errorf("unexpected counts: %+v", *progress)
+	}
+
+	want := "scanned 1 file(s), 1 matched, 0 failed\n" +
+		"scanned 2 file(s), 1 matched, 0 failed\n" +
+		"scanned 3 file(s), 2 matched, 0 failed\n"
+	if stderr != want {
+		t.Errorf("got report:\n%s\nwant:\n%s", stderr, want)
+	}
+
+	if stdout == "" {
+		t.Error("expected the scan results on stdout")
+	}
+}
+
+func TestProgressNil(t *testing.T) {
+	var progress *Progress
+
+	stderr := captureFile(t, &os.Stderr, func() {
+		progress.File(true)
+		progress.Fail()
+		progress.Clear()
+		progress.Done()
+	})
+
+	if stderr != "" {
+		t.Errorf("expected a nil progress to report nothing, got %q", stderr)
+	}
+
+	if NewProgress(false) != nil {
+		t.Error("expected NewProgress(false) to return nil")
+	}
+}